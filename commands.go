@@ -8,25 +8,57 @@ import (
 // Commands is the mapping of all the available tfvm commands.
 var Commands map[string]cli.CommandFactory
 
-func initCommands(
-	terraformVersion string,
-	installPath string,
-	binPath string,
-	tempPath string,
-	extension string,
-	ui cli.Ui,
-) {
+// meta is the Meta every command is created with.
+var meta command.Meta
 
-	meta := command.Meta{
-		TerraformVersion: terraformVersion,
-		InstallPath:      installPath,
-		BinPath:          binPath,
-		TempPath:         tempPath,
-		Extension:        extension,
-		Ui:               ui,
+// initCommands creates the commands, whose Meta is initialized by initMeta when a command first needs it.
+func initCommands(ui cli.Ui) {
+	meta = command.Meta{
+		Ui:   ui,
+		Init: initMeta,
 	}
 
 	Commands = map[string]cli.CommandFactory{
+		"cache": func() (cli.Command, error) {
+			return &command.CacheCommand{
+				Meta: meta,
+			}, nil
+		},
+		"cache clean": func() (cli.Command, error) {
+			return &command.CacheCleanCommand{
+				Meta: meta,
+			}, nil
+		},
+		"cache list": func() (cli.Command, error) {
+			return &command.CacheListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"cache size": func() (cli.Command, error) {
+			return &command.CacheSizeCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config": func() (cli.Command, error) {
+			return &command.ConfigCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config get": func() (cli.Command, error) {
+			return &command.ConfigGetCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config list": func() (cli.Command, error) {
+			return &command.ConfigListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config set": func() (cli.Command, error) {
+			return &command.ConfigSetCommand{
+				Meta: meta,
+			}, nil
+		},
 		"install": func() (cli.Command, error) {
 			return &command.InstallCommand{
 				Meta: meta,
@@ -37,11 +69,31 @@ func initCommands(
 				Meta: meta,
 			}, nil
 		},
+		"link": func() (cli.Command, error) {
+			return &command.LinkCommand{
+				Meta: meta,
+			}, nil
+		},
 		"list": func() (cli.Command, error) {
 			return &command.ListCommand{
 				Meta: meta,
 			}, nil
 		},
+		"outdated": func() (cli.Command, error) {
+			return &command.OutdatedCommand{
+				Meta: meta,
+			}, nil
+		},
+		"upgrade": func() (cli.Command, error) {
+			return &command.UpgradeCommand{
+				Meta: meta,
+			}, nil
+		},
+		"prune": func() (cli.Command, error) {
+			return &command.PruneCommand{
+				Meta: meta,
+			}, nil
+		},
 		"remove": func() (cli.Command, error) {
 			return &command.RemoveCommand{
 				Meta: meta,
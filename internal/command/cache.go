@@ -0,0 +1,203 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ehassett/tfvm/internal/helper"
+	"github.com/mitchellh/cli"
+)
+
+// CacheCommand is a Command that groups the subcommands managing the archive cache.
+type CacheCommand struct {
+	Meta
+}
+
+func (c *CacheCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *CacheCommand) Synopsis() string {
+	return "Manage cached Terraform archives"
+}
+
+func (c *CacheCommand) Help() string {
+	helpText := `
+Usage: tfvm cache <subcommand>
+
+	Manages the cache of verified Terraform archives, which installs use before downloading.
+	Archives are cached in the archives directory of the tfvm cache, or in TFVM_CACHE_DIR if it is set.
+	`
+
+	return strings.TrimSpace(helpText)
+}
+
+// CacheListCommand is a Command that lists the cached archives.
+type CacheListCommand struct {
+	Meta
+}
+
+func (c *CacheListCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	files, err := helper.GetCachedArchives()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not list cached archives: %s", err))
+		return 1
+	}
+
+	if files == nil {
+		files = []helper.CachedFile{}
+	}
+	if c.outputJSON(cacheListJSON{Archives: files}) {
+		return 0
+	}
+
+	for _, f := range files {
+		c.Ui.Output(fmt.Sprintf("%-40s %10s", f.Name, formatBytes(f.Size)))
+	}
+	return 0
+}
+
+// cacheListJSON is the result of tfvm cache list with JSON output.
+type cacheListJSON struct {
+	Archives []helper.CachedFile `json:"archives"`
+}
+
+func (c *CacheListCommand) Synopsis() string {
+	return "List cached Terraform archives"
+}
+
+func (c *CacheListCommand) Help() string {
+	helpText := `
+Usage: tfvm cache list
+
+	Lists the cached Terraform archives and checksum files with their sizes.
+	`
+
+	return strings.TrimSpace(helpText)
+}
+
+// CacheCleanCommand is a Command that removes cached archives.
+type CacheCleanCommand struct {
+	Meta
+}
+
+func (c *CacheCleanCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	files, err := helper.GetCachedArchives()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not clean cached archives: %s", err))
+		return 1
+	}
+
+	result := cacheCleanJSON{Removed: []string{}}
+	for _, f := range files {
+		if len(args) > 0 && !cachedFileMatches(f.Name, args) {
+			continue
+		}
+
+		err := helper.RemoveCachedArchive(f.Name)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Could not remove %s: %s", f.Name, err))
+			return 1
+		}
+		result.Removed = append(result.Removed, f.Name)
+		result.Freed += f.Size
+	}
+
+	if c.outputJSON(result) {
+		return 0
+	}
+	c.Ui.Output(fmt.Sprintf("Removed %d cached files, freeing %s.", len(result.Removed), formatBytes(result.Freed)))
+	return 0
+}
+
+// cacheCleanJSON is the result of tfvm cache clean with JSON output.
+type cacheCleanJSON struct {
+	Removed []string `json:"removed"`
+	Freed   int64    `json:"freed"`
+}
+
+func (c *CacheCleanCommand) Synopsis() string {
+	return "Remove cached Terraform archives"
+}
+
+func (c *CacheCleanCommand) Help() string {
+	helpText := `
+Usage: tfvm cache clean [version...]
+
+	Removes cached Terraform archives and checksum files.
+	If versions are specified, only the files for those versions are removed.
+
+	Examples:
+		tfvm cache clean	Removes every cached archive
+		tfvm cache clean 1.0.0	Removes the cached archive of Terraform v1.0.0
+	`
+
+	return strings.TrimSpace(helpText)
+}
+
+// cachedFileMatches reports whether the cached file name belongs to one of versions.
+func cachedFileMatches(name string, versions []string) bool {
+	for _, v := range versions {
+		if strings.HasPrefix(name, "terraform_"+v+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheSizeCommand is a Command that shows the size of the archive cache.
+type CacheSizeCommand struct {
+	Meta
+}
+
+func (c *CacheSizeCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	files, err := helper.GetCachedArchives()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not get cache size: %s", err))
+		return 1
+	}
+
+	result := cacheSizeJSON{Path: helper.ArchivePath(), Files: len(files)}
+	for _, f := range files {
+		result.Size += f.Size
+	}
+
+	if c.outputJSON(result) {
+		return 0
+	}
+	c.Ui.Output(fmt.Sprintf("%s in %d cached files (%s)", formatBytes(result.Size), result.Files, result.Path))
+	return 0
+}
+
+// cacheSizeJSON is the result of tfvm cache size with JSON output.
+type cacheSizeJSON struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+func (c *CacheSizeCommand) Synopsis() string {
+	return "Show the size of cached Terraform archives"
+}
+
+func (c *CacheSizeCommand) Help() string {
+	helpText := `
+Usage: tfvm cache size
+
+	Shows the total size of the cached Terraform archives and checksum files.
+	`
+
+	return strings.TrimSpace(helpText)
+}
@@ -0,0 +1,250 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ehassett/tfvm/internal/helper"
+	"github.com/mitchellh/cli"
+)
+
+// ConfigCommand is a Command that groups the subcommands managing settings.
+type ConfigCommand struct {
+	Meta
+}
+
+func (c *ConfigCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *ConfigCommand) Synopsis() string {
+	return "Manage tfvm settings"
+}
+
+func (c *ConfigCommand) Help() string {
+	helpText := `
+Usage: tfvm config <subcommand>
+
+	Manages the settings of tfvm. Settings are read from, in order of precedence:
+	command flags, TFVM_* environment variables, the .tfvm.json file of the repository
+	(in the working directory or a parent up to the repository root), and the user configuration file
+	(config.json in the tfvm config directory).
+	Only cache_ttl, auto_install, link_mode and default_version can be set in .tfvm.json,
+	the others only in the environment or the user configuration.
+
+	For a list of settings and their values, run:
+		tfvm config list
+	`
+
+	return strings.TrimSpace(helpText)
+}
+
+// configValueJSON is the value of a setting in JSON output. Source is env, repo, user or default.
+type configValueJSON struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Env    string `json:"env"`
+}
+
+// configListJSON is the result of tfvm config list with JSON output.
+type configListJSON struct {
+	Settings []configValueJSON `json:"settings"`
+	User     string            `json:"user_file"`
+	Repo     string            `json:"repo_file,omitempty"`
+}
+
+// ConfigListCommand is a Command that lists every setting and its value.
+type ConfigListCommand struct {
+	Meta
+}
+
+func (c *ConfigListCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	if err := helper.CheckConfig(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not read configuration: %s", err))
+		return 1
+	}
+
+	result := configListJSON{Settings: []configValueJSON{}, User: helper.UserConfigFile(), Repo: helper.RepoConfigFile()}
+	for _, s := range helper.Settings {
+		value, source := helper.LookupConfig(s.Key)
+		result.Settings = append(result.Settings, configValueJSON{Key: s.Key, Value: value, Source: source, Env: s.Env})
+	}
+	if c.outputJSON(result) {
+		return 0
+	}
+
+	rows := [][]string{{"KEY", "VALUE", "SOURCE"}}
+	for _, s := range result.Settings {
+		rows = append(rows, []string{s.Key, orDash(s.Value), s.Source})
+	}
+	outputTable(c.Ui, rows)
+	return 0
+}
+
+func (c *ConfigListCommand) Synopsis() string {
+	return "List tfvm settings"
+}
+
+func (c *ConfigListCommand) Help() string {
+	var settings strings.Builder
+	for _, s := range helper.Settings {
+		fmt.Fprintf(&settings, "\t\t%s\t%s (%s)\n", s.Key, s.Description, s.Env)
+	}
+
+	helpText := `
+Usage: tfvm config list
+
+	Lists every setting with its value and where the value comes from: env, repo, user or default.
+
+	Settings:
+` + settings.String()
+
+	return strings.TrimSpace(helpText)
+}
+
+// ConfigGetCommand is a Command that prints the value of a setting.
+type ConfigGetCommand struct {
+	Meta
+}
+
+func (c *ConfigGetCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	if len(args) != 1 {
+		c.Ui.Error("Could not get setting: expected a single setting name")
+		return 1
+	}
+	s, ok := helper.LookupSetting(args[0])
+	if !ok {
+		c.Ui.Error(fmt.Sprintf("Could not get setting: unknown setting %q, run `tfvm config list` for a list of settings", args[0]))
+		return 1
+	}
+	if err := helper.CheckConfig(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not read configuration: %s", err))
+		return 1
+	}
+
+	value, source := helper.LookupConfig(s.Key)
+	if c.outputJSON(configValueJSON{Key: s.Key, Value: value, Source: source, Env: s.Env}) {
+		return 0
+	}
+	if value != "" {
+		c.Ui.Output(value)
+	}
+	return 0
+}
+
+func (c *ConfigGetCommand) Synopsis() string {
+	return "Print the value of a tfvm setting"
+}
+
+func (c *ConfigGetCommand) Help() string {
+	helpText := `
+Usage: tfvm config get <key>
+
+	Prints the value of a setting, or nothing if it is not set.
+	`
+
+	return strings.TrimSpace(helpText)
+}
+
+// ConfigSetCommand is a Command that changes a setting in a configuration file.
+type ConfigSetCommand struct {
+	Meta
+}
+
+func (c *ConfigSetCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	var repo bool
+
+	cmdFlags := c.flagSet("config set")
+	cmdFlags.BoolVar(&repo, "repo", false, "repo")
+	if err := cmdFlags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse arguments: %s", err))
+		return 1
+	}
+	args = cmdFlags.Args()
+
+	if len(args) != 2 {
+		c.Ui.Error("Could not change setting: expected a setting name and a value")
+		return 1
+	}
+	key, value := args[0], args[1]
+
+	s, ok := helper.LookupSetting(key)
+	if !ok {
+		c.Ui.Error(fmt.Sprintf("Could not change setting: unknown setting %q, run `tfvm config list` for a list of settings", key))
+		return 1
+	}
+
+	path := helper.UserConfigFile()
+	if repo {
+		if !s.Repo {
+			c.Ui.Error(fmt.Sprintf("Could not change setting: %s can only be set in the user configuration", key))
+			return 1
+		}
+		if path = helper.RepoConfigFile(); path == "" {
+			path = helper.RepoConfigFileName
+		}
+	}
+	if path == "" {
+		c.Ui.Error("Could not change setting: there is no configuration directory")
+		return 1
+	}
+
+	if err := helper.SetConfig(path, key, value); err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not change setting: %s", err))
+		return 1
+	}
+	if c.outputJSON(configValueJSON{Key: key, Value: value, Source: configSource(repo), Env: s.Env}) {
+		return 0
+	}
+
+	if value == "" {
+		c.Ui.Output(fmt.Sprintf("Removed %s from %s", key, path))
+	} else {
+		c.Ui.Output(fmt.Sprintf("Set %s to %s in %s", key, value, path))
+	}
+	return 0
+}
+
+// configSource returns the source of a setting written with or without --repo.
+func configSource(repo bool) string {
+	if repo {
+		return helper.ConfigSourceRepo
+	}
+	return helper.ConfigSourceUser
+}
+
+func (c *ConfigSetCommand) Synopsis() string {
+	return "Change a tfvm setting"
+}
+
+func (c *ConfigSetCommand) Help() string {
+	helpText := `
+Usage: tfvm config set [options] <key> <value>
+
+	Sets a setting in the user configuration file, or removes it when the value is empty.
+	A TFVM_* environment variable still takes precedence over the value that is set.
+
+	Options:
+		--repo	Set it in the .tfvm.json file of the repository instead, creating one in the current directory if needed
+
+	Examples:
+		tfvm config set max_versions 5	Keeps at most five versions installed
+		tfvm config set --repo default_version 1.5	Uses the newest 1.5 release in this repository when there is no .tfversion
+		tfvm config set mirror ""	Goes back to installing from releases.hashicorp.com
+	`
+
+	return strings.TrimSpace(helpText)
+}
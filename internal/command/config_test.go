@@ -0,0 +1,104 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ehassett/tfvm/internal/helper"
+	"github.com/mitchellh/cli"
+)
+
+// TestConfig sets up a configuration directory and tests the config subcommands.
+func TestConfig(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-command-config")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	helper.ConfigPath = workDir
+	defer func() { helper.ConfigPath = "" }()
+
+	meta := func(ui cli.Ui) Meta {
+		return Meta{Ui: ui}
+	}
+
+	// Set a value and expect it to be stored in the user configuration.
+	t.Run("set", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &ConfigSetCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"max_versions", "5"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if value, source := helper.LookupConfig("max_versions"); value != "5" || source != helper.ConfigSourceUser {
+			t.Fatalf("got %q from %s, expected 5 from the user configuration", value, source)
+		}
+	})
+
+	// Get the value back and expect only the value to be printed.
+	t.Run("get", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &ConfigGetCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"max_versions"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if out := ui.OutputWriter.String(); out != "5\n" {
+			t.Fatalf("unexpected output %q", out)
+		}
+	})
+
+	// List the settings and expect the value to be shown with its source.
+	t.Run("list", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &ConfigListCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		found := false
+		for _, line := range strings.Split(ui.OutputWriter.String(), "\n") {
+			if strings.Join(strings.Fields(line), " ") == "max_versions 5 user" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected max_versions to be listed from the user configuration\nstdout: %s", ui.OutputWriter.String())
+		}
+	})
+
+	// Set verify in the repository configuration and expect an error.
+	t.Run("set verify in repository", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &ConfigSetCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"--repo", "verify", "off"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d", status)
+		}
+
+		if !strings.Contains(ui.ErrorWriter.String(), "can only be set in the user configuration") {
+			t.Fatalf("expected an error about the repository configuration\nstderr: %s", ui.ErrorWriter.String())
+		}
+	})
+
+	// Set an unknown setting and expect an error.
+	t.Run("unknown setting", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &ConfigSetCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"colour", "blue"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d", status)
+		}
+	})
+}
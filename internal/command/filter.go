@@ -0,0 +1,96 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ehassett/tfvm/internal/helper"
+)
+
+// versionFilter selects versions with the --major, --minor, --since, --regex and --limit flags.
+type versionFilter struct {
+	major string
+	minor string
+	since string
+	regex string
+	limit int
+}
+
+// addFlags registers the filter flags on f.
+func (vf *versionFilter) addFlags(f *flag.FlagSet) {
+	f.StringVar(&vf.major, "major", "", "major")
+	f.StringVar(&vf.minor, "minor", "", "minor")
+	f.StringVar(&vf.since, "since", "", "since")
+	f.StringVar(&vf.regex, "regex", "", "regex")
+	f.IntVar(&vf.limit, "limit", 0, "limit")
+}
+
+// apply returns the versions that pass every filter, in their original order.
+// Names that are not versions, such as those of linked binaries, only pass when no version filter is set.
+func (vf *versionFilter) apply(versions []string) ([]string, error) {
+	var constraints []helper.Constraint
+	add := func(flag string, value string, constraint string) error {
+		c, err := helper.ParseConstraint(constraint)
+		if err != nil {
+			return fmt.Errorf("invalid --%s %q", flag, value)
+		}
+		constraints = append(constraints, c)
+		return nil
+	}
+
+	if vf.major != "" {
+		if _, err := strconv.Atoi(vf.major); err != nil {
+			return nil, fmt.Errorf("invalid --major %q", vf.major)
+		}
+		if err := add("major", vf.major, "~> "+vf.major+".0"); err != nil {
+			return nil, err
+		}
+	}
+	if vf.minor != "" {
+		if strings.Count(vf.minor, ".") != 1 {
+			return nil, fmt.Errorf("invalid --minor %q", vf.minor)
+		}
+		if err := add("minor", vf.minor, "~> "+vf.minor+".0"); err != nil {
+			return nil, err
+		}
+	}
+	if vf.since != "" {
+		if err := add("since", vf.since, ">= "+vf.since); err != nil {
+			return nil, err
+		}
+	}
+
+	var re *regexp.Regexp
+	if vf.regex != "" {
+		var err error
+		re, err = regexp.Compile(vf.regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --regex: %w", err)
+		}
+	}
+
+	filtered := []string{}
+	for _, v := range versions {
+		if vf.limit > 0 && len(filtered) == vf.limit {
+			break
+		}
+		if re != nil && !re.MatchString(v) {
+			continue
+		}
+
+		matches := true
+		for _, c := range constraints {
+			if !c.Check(v) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered, nil
+}
@@ -2,15 +2,18 @@ package command
 
 import (
 	"archive/zip"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/ehassett/tfvm/internal/helper"
 )
@@ -21,22 +24,59 @@ type InstallCommand struct {
 }
 
 func (c *InstallCommand) Run(args []string) int {
-	if len(args) < 1 {
-		err := installLatest(c.TerraformVersion, c.InstallPath, c.BinPath, c.TempPath, c.Extension)
-		if err != nil {
-			c.Ui.Error(fmt.Sprintf("Could not install latest version: %s", err))
+	if !c.initialize() {
+		return 1
+	}
+
+	var list, quiet bool
+	var fromFile, fromURL string
+	var parallelism int
+	var filter versionFilter
+
+	cmdFlags := c.flagSet("install")
+	cmdFlags.BoolVar(&list, "list", false, "list")
+	cmdFlags.BoolVar(&list, "l", false, "list")
+	cmdFlags.BoolVar(&quiet, "quiet", false, "quiet")
+	cmdFlags.BoolVar(&quiet, "q", false, "quiet")
+	cmdFlags.BoolVar(&helper.Refresh, "refresh", false, "refresh")
+	cmdFlags.StringVar(&fromFile, "from-file", "", "from-file")
+	cmdFlags.StringVar(&fromURL, "from-url", "", "from-url")
+	cmdFlags.IntVar(&parallelism, "parallelism", 4, "parallelism")
+	filter.addFlags(cmdFlags)
+	if err := cmdFlags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse arguments: %s", err))
+		return 1
+	}
+	args = cmdFlags.Args()
+
+	if fromFile != "" || fromURL != "" {
+		if (fromFile != "" && fromURL != "") || len(args) > 0 {
+			c.Ui.Error("Could not install from source: --from-file and --from-url cannot be combined with each other or a version")
 			return 1
 		}
-		return 0
+
+		version, err := installSource(c.InstallPath, c.Extension, fromFile, fromURL, newProgress(c.Ui, quiet))
+		if err != nil && !errors.Is(err, errAlreadyInstalled) {
+			c.Ui.Error(fmt.Sprintf("Could not install from source: %s", err))
+			return 1
+		}
+		return c.outputInstalled(installResult{spec: fromFile + fromURL, version: version, installed: err == nil})
 	}
 
-	switch args[0] {
-	case "--list", "-list", "-l":
+	if list {
 		versions, err := helper.GetAvailableVersions()
 		if err != nil {
 			c.Ui.Error(fmt.Sprintf("Could not show available versions: %s", err))
 			return 1
 		}
+		versions, err = filter.apply(versions)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Could not filter versions: %s", err))
+			return 1
+		}
+		if c.outputJSON(availableJSON{Versions: versions}) {
+			return 0
+		}
 
 		pager := os.Getenv("PAGER")
 		if pager != "" {
@@ -52,15 +92,105 @@ func (c *InstallCommand) Run(args []string) int {
 				c.Ui.Output(versions[i])
 			}
 		}
+		return 0
+	}
 
-	default:
-		err := installVersion(c.TerraformVersion, c.InstallPath, c.BinPath, c.TempPath, c.Extension, args[0])
+	if len(args) < 1 {
+		// Install the version pinned by the project, falling back to the configured default and then the latest.
+		pinned, err := getProjectVersion()
 		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to read .tfversion: %s", err))
+			return 1
+		}
+		if pinned == "" {
+			pinned = helper.ConfigValue("default_version")
+		}
+		if pinned == "" {
+			pinned = "latest"
+		}
+		args = []string{pinned}
+	}
+
+	if len(args) > 1 {
+		return c.installMany(args, parallelism, quiet)
+	}
+
+	version, err := installVersion(c.TerraformVersion, c.InstallPath, c.BinPath, c.Extension, args[0], newProgress(c.Ui, quiet))
+	if err != nil && !errors.Is(err, errAlreadyInstalled) {
+		if args[0] == "latest" {
+			c.Ui.Error(fmt.Sprintf("Could not install latest version: %s", err))
+		} else {
 			c.Ui.Error(fmt.Sprintf("Could not install specified version: %s", err))
+		}
+		return 1
+	}
+	return c.outputInstalled(installResult{spec: args[0], version: version, installed: err == nil})
+}
+
+// outputInstalled reports a single version that was installed or found to be installed already,
+// after removing versions beyond the retention limits.
+func (c *InstallCommand) outputInstalled(r installResult) int {
+	evicted := c.enforceLimits(r.version)
+	if c.outputJSON(installJSON{Versions: []installResultJSON{r.json()}, Evicted: evicted}) {
+		return 0
+	}
+	if r.installed {
+		c.Ui.Output(fmt.Sprintf("Terraform v%s successfully installed. Run `tfvm use %s` to use this new version.", r.version, r.version))
+	} else {
+		c.Ui.Output(fmt.Sprintf("Terraform v%s is already installed. Run `tfvm use %s` to use this version.", r.version, r.version))
+	}
+	c.outputEvicted(evicted)
+	return 0
+}
+
+// installMany installs several version specifications in parallel and summarizes the results.
+func (c *InstallCommand) installMany(specs []string, parallelism int, quiet bool) int {
+	// Concurrent downloads are reported line by line, since a single redrawn line cannot show them all.
+	progress := newProgress(lineUi{c.Ui}, quiet)
+	results := installVersions(c.TerraformVersion, c.InstallPath, c.BinPath, c.Extension, specs, parallelism, progress)
+
+	var failed int
+	var installed []string
+	result := installJSON{Versions: []installResultJSON{}}
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+		} else {
+			installed = append(installed, r.version)
+		}
+		result.Versions = append(result.Versions, r.json())
+	}
+	result.Evicted = c.enforceLimits(installed...)
+	if c.outputJSON(result) {
+		if failed > 0 {
 			return 1
 		}
-		c.Ui.Output(fmt.Sprintf("Terraform v%s successfully installed. Run `tfvm use %s` to use this new version.", args[0], args[0]))
+		return 0
+	}
+
+	for _, r := range results {
+		name := r.version
+		if r.version != r.spec && r.version != "" {
+			name = fmt.Sprintf("%s (%s)", r.version, r.spec)
+		}
+
+		switch {
+		case r.err != nil:
+			c.Ui.Error(fmt.Sprintf("  %s: failed: %s", r.spec, r.err))
+		case r.installed:
+			c.Ui.Output(fmt.Sprintf("  %s: installed", name))
+		default:
+			c.Ui.Output(fmt.Sprintf("  %s: already installed", name))
+		}
 	}
+
+	c.outputEvicted(result.Evicted)
+
+	if failed > 0 {
+		c.Ui.Error(fmt.Sprintf("Could not install %d of %d versions.", failed, len(results)))
+		return 1
+	}
+	c.Ui.Output(fmt.Sprintf("All %d versions are installed.", len(results)))
 	return 0
 }
 
@@ -70,99 +200,489 @@ func (c *InstallCommand) Synopsis() string {
 
 func (c *InstallCommand) Help() string {
 	helpText := `
-Usage: tfvm install [version]
+Usage: tfvm install [options] [version...]
 
 	Installs a Terraform binary according to the specified version.
-	If no version is specified, tfvm will default to the latest available version.
-	Version specification can be to the patch or minor version.
-	Only specifying a minor version will install the latest patch of that version.
+	If no version is specified, tfvm will install the version in .tfversion if it exists in the current directory,
+	and will otherwise default to the default_version setting or the latest available version.
+	Version specification can be to the patch or minor version, or a version constraint such as '~> 1.6'.
+	Only specifying a minor version will install the latest patch of that version,
+	and a constraint will install the latest version that meets it.
+	Several versions can be installed at once, in which case they are downloaded in parallel.
 
 	For a list of available versions, run:
   	tfvm install --list
 
 	Options:
-		--list, -l	List available versions of Terraform
+		--list, -l	List available versions of Terraform, filtered as with tfvm list --remote
+		--quiet, -q	Do not report download progress
+		--refresh	Fetch the list of available versions again instead of using the cached copy
+		--from-file	Install from a local release archive instead of the mirror
+		--from-url	Install from a release archive at any URL instead of the mirror
+		--parallelism	Number of versions to download at once (default 4)
 
 	Examples:
 		tfvm install 1.0.0	Installs Terraform v1.0.0
 		tfvm install 1.0	Installs the latest of Terraform v1.0.x
+		tfvm install latest	Installs the latest available version of Terraform
+		tfvm install 1.4 1.5.7 '~> 1.6'	Installs the latest v1.4.x, v1.5.7 and the latest v1.x from v1.6
+		tfvm install --from-file ./terraform_1.5.7_linux_amd64.zip	Installs Terraform v1.5.7 from a local archive
 	`
 
 	return strings.TrimSpace(helpText)
 }
 
-// installVersion verifies and installs the specified version of Terraform.
+// errAlreadyInstalled is returned when the version to install is already installed.
+// Callers treat it as success, since the version is there to be used.
+var errAlreadyInstalled = errors.New("already installed")
+
+// installVersion verifies and installs the specified version of Terraform, and returns the resolved version.
 func installVersion(
 	currentVersion string,
 	installPath string,
 	binPath string,
-	tempPath string,
 	extension string,
 	version string,
-) error {
-	if strings.Count(version, ".") == 1 {
-		fullVersion, err := getMinorVersion(version)
-		if err != nil {
-			return err
-		}
-		version = fullVersion
+	progress progressFactory,
+) (string, error) {
+	version, err := resolveVersion(version)
+	if err != nil {
+		return version, err
 	}
 
 	// Check if the selected version is already installed.
-	_, err := os.Stat(installPath + string(filepath.Separator) + "terraform" + version + extension)
+	_, err = os.Stat(helper.VersionDir(installPath, version))
 	if !os.IsNotExist(err) {
-		err = errors.New("already installed, run `tfvm use " + version + "` to use this version")
-		return err
+		return version, errAlreadyInstalled
 	}
 
-	// Check if the selected version is available to install.
-	err = helper.IsAvailableVersion(version)
+	arch, err := getArchitecture()
 	if err != nil {
-		return err
+		return version, err
 	}
 
-	arch, err := getArchitecture()
+	// Download and extract into a staging directory unique to this install.
+	staging, err := helper.NewStagingDir(installPath)
 	if err != nil {
-		return err
+		return version, err
 	}
+	defer os.RemoveAll(staging)
 
-	url := "https://releases.hashicorp.com/terraform/" + version + "/terraform_" + version + "_" + arch + ".zip"
-	err = downloadArchive(url, tempPath)
+	archivePath, verified, err := fetchArchive(version, arch, staging, progress)
 	if err != nil {
-		return err
+		return version, err
 	}
 
-	err = unzipArchive(tempPath, installPath)
+	extractPath := staging + string(filepath.Separator) + "extract"
+	err = unzipArchive(archivePath, extractPath)
+	if err != nil {
+		return version, err
+	}
+
+	manifest := helper.NewManifest(version, helper.SourceMirror)
+	manifest.URL = helper.MirrorURL() + version + "/" + helper.ArchiveName(version, arch)
+	manifest.Verified = verified
+	return version, registerVersion(installPath, archivePath, extractPath, manifest)
+}
+
+// installResult is the outcome of installing one version specification.
+type installResult struct {
+	spec      string
+	version   string
+	installed bool
+	err       error
+}
+
+// json returns the result as it is shown in JSON output.
+func (r installResult) json() installResultJSON {
+	result := installResultJSON{Spec: r.spec, Version: r.version, Installed: r.installed}
+	if r.err != nil {
+		result.Error = r.err.Error()
+	}
+	return result
+}
+
+// installJSON is the result of tfvm install with JSON output.
+// Evicted lists the versions removed to stay within the retention limits.
+type installJSON struct {
+	Versions []installResultJSON `json:"versions"`
+	Evicted  []string            `json:"evicted,omitempty"`
+}
+
+// installResultJSON is the outcome of installing one version specification in JSON output.
+// Installed is false for versions that were already installed or failed with Error.
+type installResultJSON struct {
+	Spec      string `json:"spec"`
+	Version   string `json:"version"`
+	Installed bool   `json:"installed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// availableJSON is the result of tfvm install --list with JSON output.
+type availableJSON struct {
+	Versions []string `json:"versions"`
+}
+
+// installVersions installs several version specifications, downloading up to parallelism versions at once.
+// Every specification is resolved against the same catalog before anything is downloaded.
+func installVersions(
+	currentVersion string,
+	installPath string,
+	binPath string,
+	extension string,
+	specs []string,
+	parallelism int,
+	progress progressFactory,
+) []installResult {
+	results := make([]installResult, len(specs))
+	owner := map[string]int{}
+	duplicates := map[int]int{}
+	var pending []int
+	for i, spec := range specs {
+		version, err := resolveVersion(spec)
+		results[i] = installResult{spec: spec, version: version, err: err}
+		if err != nil || helper.IsInstalledVersion(installPath, extension, version) == nil {
+			continue
+		}
+
+		// Install each version once, however many specifications resolve to it.
+		if first, ok := owner[version]; ok {
+			duplicates[i] = first
+			continue
+		}
+		owner[version] = i
+		pending = append(pending, i)
+	}
+
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, i := range pending {
+		wg.Add(1)
+		go func(r *installResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			_, err := installVersion(currentVersion, installPath, binPath, extension, r.version, progress)
+			if !errors.Is(err, errAlreadyInstalled) {
+				r.installed = err == nil
+				r.err = err
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+
+	// Specifications that resolved to a version installed for an earlier one share its outcome.
+	for i, first := range duplicates {
+		results[i].installed = results[first].installed
+		results[i].err = results[first].err
+	}
+	return results
+}
+
+// resolveVersion resolves a version specification to a full version.
+// A specification is "latest", a minor version such as 1.5, a constraint such as "~> 1.6", or a full version,
+// which is returned as is.
+func resolveVersion(spec string) (string, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch {
+	case spec == "latest":
+		versions, err := helper.GetAvailableVersions()
+		if err != nil {
+			return spec, err
+		}
+		if len(versions) == 0 {
+			return spec, errors.New("no versions are available")
+		}
+		return versions[0], nil
+
+	case helper.IsConstraint(spec):
+		constraint, err := helper.ParseConstraint(spec)
+		if err != nil {
+			return spec, err
+		}
+
+		versions, err := helper.GetAvailableVersions()
+		if err != nil {
+			return spec, err
+		}
+		for _, v := range versions {
+			if constraint.Check(v) {
+				return v, nil
+			}
+		}
+		return spec, errors.New("no available version meets " + spec + ", run `tfvm install --list` to see all available versions")
+
+	case strings.Count(spec, ".") == 1:
+		return getMinorVersion(spec)
+	}
+
+	return spec, nil
+}
+
+// registerVersion writes the manifest into the archive contents extracted to extractPath
+// and moves them into the install path as the version directory.
+func registerVersion(installPath string, archivePath string, extractPath string, manifest *helper.Manifest) error {
+	sum, err := helper.FileSum(archivePath)
 	if err != nil {
 		return err
 	}
-	err = os.Remove(tempPath)
+	manifest.SHA256 = sum
+
+	err = helper.WriteManifest(extractPath, manifest)
 	if err != nil {
 		return err
 	}
-	err = os.Rename(installPath+string(filepath.Separator)+"terraform"+extension, installPath+string(filepath.Separator)+"terraform"+version+extension)
+
+	lock, err := helper.LockExclusive(installPath)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 
-	return nil
+	// Another process may have finished installing the same version in the meantime.
+	dest := helper.VersionDir(installPath, manifest.Version)
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+
+	// Move the version into place in a single step so a partial install is never visible.
+	return os.Rename(extractPath, dest)
+}
+
+// installSource installs Terraform from a local archive file or from an archive at rawURL, and returns the installed version.
+// The version is read from the archive name, or from the extracted binary if the name does not include it.
+// The archive is verified when a checksums file is found next to it.
+func installSource(
+	installPath string,
+	extension string,
+	file string,
+	rawURL string,
+	progress progressFactory,
+) (string, error) {
+	staging, err := helper.NewStagingDir(installPath)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	var archivePath, name, sumsPath string
+	if file != "" {
+		archivePath = file
+		name = filepath.Base(file)
+	} else {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", err
+		}
+		name = path.Base(u.Path)
+		archivePath = staging + string(filepath.Separator) + name
+		err = downloadArchive(rawURL, archivePath, progress(name))
+		if err != nil {
+			return "", err
+		}
+	}
+	version := archiveVersion(name)
+
+	verified := false
+	if helper.VerifyPolicy() != helper.VerifyOff {
+		if file != "" {
+			sumsPath = findLocalSums(file, version)
+		} else {
+			sumsPath = fetchRemoteSums(rawURL, version, staging)
+		}
+		if sumsPath != "" {
+			err = helper.VerifyArchive(archivePath, sumsPath, name)
+			if err != nil {
+				return "", err
+			}
+			verified = true
+		}
+	}
+
+	extractPath := staging + string(filepath.Separator) + "extract"
+	err = unzipArchive(archivePath, extractPath)
+	if err != nil {
+		return "", err
+	}
+
+	if version == "" {
+		version, err = binaryVersion(extractPath + string(filepath.Separator) + "terraform" + extension)
+		if err != nil {
+			return "", fmt.Errorf("could not determine the version of the archive: %w", err)
+		}
+	}
+
+	if helper.IsInstalledVersion(installPath, extension, version) == nil {
+		return version, errAlreadyInstalled
+	}
+
+	manifest := helper.NewManifest(version, helper.SourceFile)
+	if file == "" {
+		manifest.Source = helper.SourceURL
+		manifest.URL = rawURL
+	} else if abs, err := filepath.Abs(file); err == nil {
+		manifest.URL = "file://" + filepath.ToSlash(abs)
+	}
+	manifest.Verified = verified
+	return version, registerVersion(installPath, archivePath, extractPath, manifest)
+}
+
+// archiveVersion reads the version from a release archive name such as terraform_1.5.7_linux_amd64.zip.
+// It returns an empty string if the name does not follow that pattern.
+func archiveVersion(name string) string {
+	fields := strings.Split(strings.TrimSuffix(name, ".zip"), "_")
+	if len(fields) < 2 || fields[0] != "terraform" || !strings.Contains(fields[1], ".") {
+		return ""
+	}
+	return fields[1]
+}
+
+// findLocalSums returns the checksums file next to the archive at file, or an empty string if there is none.
+func findLocalSums(file string, version string) string {
+	dir := filepath.Dir(file)
+
+	candidates := []string{"SHA256SUMS"}
+	if version != "" {
+		candidates = append([]string{helper.SumsName(version)}, candidates...)
+	}
+	for _, name := range candidates {
+		sumsPath := dir + string(filepath.Separator) + name
+		if _, err := os.Stat(sumsPath); err == nil {
+			return sumsPath
+		}
+	}
+	return ""
 }
 
-// installLatest installs the newest available version of Terraform.
-func installLatest(
+// fetchRemoteSums downloads the checksums file published next to the archive at rawURL into staging.
+// It returns an empty string if there is none.
+func fetchRemoteSums(rawURL string, version string, staging string) string {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	candidates := []string{"SHA256SUMS"}
+	if version != "" {
+		candidates = append([]string{helper.SumsName(version)}, candidates...)
+	}
+	for _, name := range candidates {
+		sumsPath := staging + string(filepath.Separator) + name
+		sumsURL := base.ResolveReference(&url.URL{Path: name}).String()
+		if err := downloadArchive(sumsURL, sumsPath, nil); err == nil {
+			return sumsPath
+		}
+		os.Remove(sumsPath)
+	}
+	return ""
+}
+
+// binaryVersion runs the Terraform binary at path to find its version.
+func binaryVersion(path string) (string, error) {
+	out, err := exec.Command(path, "version", "-json").Output()
+	if err == nil {
+		var v struct {
+			Version string `json:"terraform_version"`
+		}
+		if json.Unmarshal(out, &v) == nil && v.Version != "" {
+			return v.Version, nil
+		}
+	}
+
+	// Versions before 0.13 do not support -json.
+	out, err = exec.Command(path, "version").Output()
+	if err != nil {
+		return "", err
+	}
+	line := strings.SplitN(string(out), "\n", 2)[0]
+	if !strings.HasPrefix(line, "Terraform v") {
+		return "", fmt.Errorf("unexpected version output %q", line)
+	}
+	return strings.TrimPrefix(line, "Terraform v"), nil
+}
+
+// fetchArchive returns the path of the release archive of version for arch, taken from the
+// archive cache if possible and otherwise downloaded into staging and verified per TFVM_VERIFY.
+// It also reports whether the archive was verified; cached archives always were.
+func fetchArchive(version string, arch string, staging string, progress progressFactory) (string, bool, error) {
+	if cached, ok := helper.CachedArchive(version, arch); ok {
+		return cached, true, nil
+	}
+
+	// Check if the selected version is available to install.
+	err := helper.IsAvailableVersion(version)
+	if err != nil {
+		return "", false, err
+	}
+
+	name := helper.ArchiveName(version, arch)
+	archivePath := staging + string(filepath.Separator) + name
+	err = downloadArchive(helper.MirrorURL()+version+"/"+name, archivePath, progress(name))
+	if err != nil {
+		return "", false, err
+	}
+
+	policy := helper.VerifyPolicy()
+	if policy == helper.VerifyOff {
+		return archivePath, false, nil
+	}
+
+	sumsName := helper.SumsName(version)
+	sumsPath := staging + string(filepath.Separator) + sumsName
+	err = downloadArchive(helper.MirrorURL()+version+"/"+sumsName, sumsPath, progress(sumsName))
+	if err != nil {
+		if policy == helper.VerifyOptional {
+			return archivePath, false, nil
+		}
+		return "", false, fmt.Errorf("could not download checksums: %w", err)
+	}
+
+	err = helper.VerifyArchive(archivePath, sumsPath, name)
+	if err != nil {
+		return "", false, err
+	}
+
+	// Keep the verified archive for later installs. Failing to do so only costs a download.
+	helper.CacheArchive(archivePath, sumsPath, version)
+	return archivePath, true, nil
+}
+
+// ensureInstalled installs the specified version of Terraform if it is not already installed.
+// It returns the resolved version and whether an install took place.
+func ensureInstalled(
 	currentVersion string,
 	installPath string,
 	binPath string,
-	tempPath string,
 	extension string,
-) error {
-	versions, err := helper.GetAvailableVersions()
+	version string,
+	progress progressFactory,
+) (string, bool, error) {
+	if helper.IsInstalledVersion(installPath, extension, version) == nil {
+		return version, false, nil
+	}
+
+	// Resolve a minor specification or constraint before checking again.
+	version, err := resolveVersion(version)
 	if err != nil {
-		return err
+		return version, false, err
+	}
+	if helper.IsInstalledVersion(installPath, extension, version) == nil {
+		return version, false, nil
 	}
 
-	err = installVersion(currentVersion, installPath, binPath, tempPath, extension, versions[0])
-	return err
+	_, err = installVersion(currentVersion, installPath, binPath, extension, version, progress)
+	if errors.Is(err, errAlreadyInstalled) {
+		return version, false, nil
+	}
+	if err != nil {
+		return version, false, err
+	}
+	return version, true, nil
 }
 
 // getArchitecture determines OS and Arch information.
@@ -208,22 +728,9 @@ func getArchitecture() (string, error) {
 	return arch, err
 }
 
-// downloadArchive downloads the Zip at the specified URL.
-func downloadArchive(url string, tempPath string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	out, err := os.Create(tempPath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
+// downloadArchive downloads the file at the specified URL to dest, reporting to progress if it is not nil.
+func downloadArchive(url string, dest string, progress helper.ProgressFunc) error {
+	return helper.Download(url, dest, progress)
 }
 
 // unzipArchive unzips the Zip at src to the path at dest.
@@ -276,15 +783,20 @@ func unzipArchive(src string, dest string) error {
 }
 
 // getMinorVersion gets the latest Terrform version from a minor version.
+// The minor version is read as the constraint "~> 1.1.0", as tfvm prune and tfvm outdated do, so 1.1 never selects 1.10.
 func getMinorVersion(version string) (string, error) {
+	constraint, err := helper.ParseConstraint("~> " + version + ".0")
+	if err != nil {
+		return "", err
+	}
+
 	versions, err := helper.GetAvailableVersions()
 	if err != nil {
 		return "", err
 	}
 
 	for i := 0; i < len(versions); i++ {
-		tmp := strings.Split(versions[i], ".")[0] + "." + strings.Split(versions[i], ".")[1]
-		if strings.Contains(tmp, version) {
+		if constraint.Check(versions[i]) {
 			return versions[i], nil
 		}
 	}
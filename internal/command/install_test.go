@@ -1,11 +1,21 @@
 package command
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ehassett/tfvm/internal/helper"
 	"github.com/mitchellh/cli"
 )
 
@@ -33,11 +43,7 @@ func TestInstall(t *testing.T) {
 		t.Fatalf("cannot create stub binary: %s", err)
 	}
 
-	currentVerFileName := installDir + string(filepath.Separator) + "terraform1.0.0"
-	currentVerFile, err := os.Create(currentVerFileName)
-	if err != nil {
-		t.Fatalf("cannot create stub version file: %s", err)
-	}
+	currentVerFileName := stubVersion(t, installDir, "1.0.0")
 
 	installTestCase := func(test func(t *testing.T, c *InstallCommand, ui *cli.MockUi)) func(t *testing.T) {
 		return func(t *testing.T) {
@@ -48,7 +54,6 @@ func TestInstall(t *testing.T) {
 					TerraformVersion: "1.0.0",
 					InstallPath:      installDir,
 					BinPath:          binDir,
-					TempPath:         "tfvm.zip",
 					Extension:        "",
 					Ui:               ui,
 				},
@@ -58,14 +63,18 @@ func TestInstall(t *testing.T) {
 		}
 	}
 
-	// Pass in the current version and expect graceful error.
+	// Pass in the current version and expect a notice that it is already installed.
 	t.Run("current terraform version", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
 		status := c.Run([]string{"1.0.0"})
-		if status != 1 {
+		if status != 0 {
 			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(currentVerFile.Name()); os.IsNotExist(err) {
+		if !strings.Contains(ui.OutputWriter.String(), "already installed") {
+			t.Fatalf("expected a notice that the version is already installed\nstdout: %s", ui.OutputWriter.String())
+		}
+
+		if _, err := os.Stat(currentVerFileName); os.IsNotExist(err) {
 			t.Fatalf("unexpectedly removed the previous version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 
@@ -81,7 +90,7 @@ func TestInstall(t *testing.T) {
 			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(installDir + string(filepath.Separator) + "terraform1.0.2"); os.IsNotExist(err) {
+		if _, err := os.Stat(installDir + string(filepath.Separator) + "1.0.2"); os.IsNotExist(err) {
 			t.Fatalf("failed to install new version\nstderr: %s", ui.ErrorWriter.String())
 		}
 	}))
@@ -93,7 +102,7 @@ func TestInstall(t *testing.T) {
 			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(currentVerFile.Name()); os.IsNotExist(err) {
+		if _, err := os.Stat(currentVerFileName); os.IsNotExist(err) {
 			t.Fatalf("unexpectedly removed the previous version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 
@@ -102,6 +111,34 @@ func TestInstall(t *testing.T) {
 		}
 	}))
 
+	// Pass in no version with a .tfversion pinning the current version and expect a notice that it is already installed.
+	t.Run("pinned terraform version", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("failed to get working directory\nstderr: %s", ui.ErrorWriter.String())
+		}
+
+		tfversionFile, err := os.Create(cwd + string(filepath.Separator) + ".tfversion")
+		if err != nil {
+			t.Fatalf("cannot create stub .tfversion file: %s", err)
+		}
+		defer os.Remove(tfversionFile.Name())
+
+		_, err = tfversionFile.WriteString("1.0.0\n")
+		if err != nil {
+			t.Fatalf("cannot write stub .tfversion file: %s", err)
+		}
+
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.OutputWriter.String(), "v1.0.0 is already installed") {
+			t.Fatalf("expected the pinned version to be resolved\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
 	// Pass in an no version and expect the latest to be installed.
 	t.Run("no specified version", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
 		status := c.Run([]string{})
@@ -109,7 +146,7 @@ func TestInstall(t *testing.T) {
 			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(currentVerFile.Name()); os.IsNotExist(err) {
+		if _, err := os.Stat(currentVerFileName); os.IsNotExist(err) {
 			t.Fatalf("unexpectedly removed the previous version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 
@@ -118,3 +155,318 @@ func TestInstall(t *testing.T) {
 		}
 	}))
 }
+
+// TestInstallFromMirror serves releases from a local mirror and tests installing through the archive cache.
+func TestInstallFromMirror(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-command-install-mirror")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	installDir, err := ioutil.TempDir(workDir, "versions")
+	if err != nil {
+		t.Fatalf("cannot create versions directory: %s", err)
+	}
+
+	binDir, err := ioutil.TempDir(workDir, "bin")
+	if err != nil {
+		t.Fatalf("cannot create bin directory: %s", err)
+	}
+
+	arch, err := getArchitecture()
+	if err != nil {
+		t.Skipf("unsupported platform: %s", err)
+	}
+
+	server := newMirror(t, arch, "1.5.7", "1.5.6")
+	defer server.Close()
+
+	os.Setenv("TFVM_MIRROR", server.URL)
+	defer os.Unsetenv("TFVM_MIRROR")
+	helper.CachePath = workDir + string(filepath.Separator) + "cache"
+	defer func() { helper.CachePath = "" }()
+
+	installTestCase := func(test func(t *testing.T, c *InstallCommand, ui *cli.MockUi)) func(t *testing.T) {
+		return func(t *testing.T) {
+			ui := new(cli.MockUi)
+
+			c := &InstallCommand{
+				Meta: Meta{
+					InstallPath: installDir,
+					BinPath:     binDir,
+					Extension:   "",
+					Ui:          ui,
+				},
+			}
+
+			test(t, c, ui)
+		}
+	}
+
+	// Install from the mirror and expect the binary to be installed and the archive cached.
+	t.Run("install from mirror", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--quiet", "1.5.7"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Stat(installDir + string(filepath.Separator) + "1.5.7"); os.IsNotExist(err) {
+			t.Fatalf("failed to install new version\nstderr: %s", ui.ErrorWriter.String())
+		}
+
+		if _, ok := helper.CachedArchive("1.5.7", arch); !ok {
+			t.Fatalf("failed to cache the verified archive")
+		}
+
+		manifest, err := helper.ReadManifest(installDir, "1.5.7")
+		if err != nil || manifest.Source != helper.SourceMirror || !manifest.Verified {
+			t.Fatalf("expected a manifest for the verified mirror install, got %+v (%v)", manifest, err)
+		}
+
+		versions, _ := helper.GetInstalledVersions(installDir, "")
+		for _, v := range versions {
+			if v == "LICENSE.txt" {
+				t.Fatalf("unexpectedly listed the archive license as a version")
+			}
+		}
+	}))
+
+	// Remove the version and expect it to be reinstalled from the archive cache while offline.
+	t.Run("reinstall from cache", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		if err := os.RemoveAll(installDir + string(filepath.Separator) + "1.5.7"); err != nil {
+			t.Fatalf("cannot remove installed version: %s", err)
+		}
+
+		helper.Offline = true
+		defer func() { helper.Offline = false }()
+
+		status := c.Run([]string{"--quiet", "1.5.7"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+	}))
+
+	// Install from a local archive with checksums next to it and expect the version to be read from its name.
+	t.Run("install from file", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		archive := stubArchive(t)
+		name := helper.ArchiveName("1.4.0", arch)
+		file := workDir + string(filepath.Separator) + name
+		if err := ioutil.WriteFile(file, archive, 0644); err != nil {
+			t.Fatalf("cannot write archive: %s", err)
+		}
+		sums := fmt.Sprintf("%x  %s\n", sha256.Sum256(archive), name)
+		if err := ioutil.WriteFile(workDir+string(filepath.Separator)+"SHA256SUMS", []byte(sums), 0644); err != nil {
+			t.Fatalf("cannot write checksums: %s", err)
+		}
+
+		status := c.Run([]string{"--from-file", file})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Stat(installDir + string(filepath.Separator) + "1.4.0"); os.IsNotExist(err) {
+			t.Fatalf("failed to install archive\nstderr: %s", ui.ErrorWriter.String())
+		}
+	}))
+
+	// Install from a URL whose published checksum does not match and expect an error.
+	t.Run("install from url", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--quiet", "--from-url", server.URL + "/1.5.6/" + helper.ArchiveName("1.5.6", arch)})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.ErrorWriter.String(), "checksum mismatch") {
+			t.Fatalf("expected a checksum error\nstderr: %s", ui.ErrorWriter.String())
+		}
+	}))
+
+	// Install several versions and expect a summary with a failure for the unverifiable one.
+	t.Run("install several versions", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--quiet", "1.5", "~> 1.4.0", "1.5.6"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.OutputWriter.String(), "1.5.7 (1.5): already installed") {
+			t.Fatalf("expected the minor version to resolve to an installed version\nstdout: %s", ui.OutputWriter.String())
+		}
+
+		if !strings.Contains(ui.ErrorWriter.String(), "~> 1.4.0: failed") || !strings.Contains(ui.ErrorWriter.String(), "1.5.6: failed") {
+			t.Fatalf("expected failures for the unavailable and unverifiable versions\nstderr: %s", ui.ErrorWriter.String())
+		}
+	}))
+
+	// Install a version whose archive does not match its checksum and expect an error.
+	t.Run("checksum mismatch", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--quiet", "1.5.6"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.ErrorWriter.String(), "checksum mismatch") {
+			t.Fatalf("expected a checksum error\nstderr: %s", ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Stat(installDir + string(filepath.Separator) + "1.5.6"); !os.IsNotExist(err) {
+			t.Fatalf("unexpectedly installed an unverified version")
+		}
+	}))
+
+	// Install two specifications that resolve to the same unverifiable version and expect both to fail.
+	t.Run("duplicate specifications", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--quiet", "1.5.6", "<= 1.5.6"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.ErrorWriter.String(), "  1.5.6: failed") || !strings.Contains(ui.ErrorWriter.String(), "<= 1.5.6: failed") {
+			t.Fatalf("expected both specifications to fail\nstdout: %s\nstderr: %s", ui.OutputWriter.String(), ui.ErrorWriter.String())
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), "Could not install 2 of 2 versions.") {
+			t.Fatalf("expected both failures to be counted\nstderr: %s", ui.ErrorWriter.String())
+		}
+	}))
+
+	// Download without --quiet and expect progress on stderr only, since the output is not a terminal.
+	t.Run("progress on stderr", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		if err := os.RemoveAll(installDir + string(filepath.Separator) + "1.5.7"); err != nil {
+			t.Fatalf("cannot remove installed version: %s", err)
+		}
+		if err := os.RemoveAll(helper.ArchivePath()); err != nil {
+			t.Fatalf("cannot remove cached archives: %s", err)
+		}
+
+		status := c.Run([]string{"1.5.7"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if strings.Contains(ui.OutputWriter.String(), "Downloading") || !strings.Contains(ui.ErrorWriter.String(), "Downloading") {
+			t.Fatalf("expected progress on stderr only\nstdout: %s\nstderr: %s", ui.OutputWriter.String(), ui.ErrorWriter.String())
+		}
+	}))
+
+	// Install beyond TFVM_MAX_VERSIONS and expect the least recently used version to be removed.
+	t.Run("evict least recently used", installTestCase(func(t *testing.T, c *InstallCommand, ui *cli.MockUi) {
+		old := time.Now().Add(-24 * time.Hour)
+		stubVersion(t, installDir, "1.3.0")
+		m, _ := helper.ReadManifest(installDir, "1.3.0")
+		m.LastUsed = &old
+		if err := helper.WriteManifest(helper.VersionDir(installDir, "1.3.0"), m); err != nil {
+			t.Fatalf("cannot write stub manifest: %s", err)
+		}
+		if err := os.RemoveAll(installDir + string(filepath.Separator) + "1.5.7"); err != nil {
+			t.Fatalf("cannot remove installed version: %s", err)
+		}
+
+		os.Setenv("TFVM_MAX_VERSIONS", "2")
+		defer os.Unsetenv("TFVM_MAX_VERSIONS")
+
+		status := c.Run([]string{"--quiet", "1.5.7"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		versions, _ := helper.GetInstalledVersions(installDir, "")
+		if !reflect.DeepEqual(versions, []string{"1.4.0", "1.5.7"}) {
+			t.Fatalf("expected the least recently used version to be removed, got %v", versions)
+		}
+
+		if !strings.Contains(ui.OutputWriter.String(), "Terraform v1.3.0 was removed as the least recently used version") {
+			t.Fatalf("expected the removed version to be reported\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+}
+
+// TestResolveVersion serves releases from a local mirror and tests resolving version specifications.
+func TestResolveVersion(t *testing.T) {
+	arch, err := getArchitecture()
+	if err != nil {
+		t.Skipf("unsupported platform: %s", err)
+	}
+
+	// 1.10.x is listed before 1.1.x, as newer releases are.
+	server := newMirror(t, arch, "1.10.1", "1.10.0", "1.1.9", "1.1.8")
+	defer server.Close()
+	os.Setenv("TFVM_MIRROR", server.URL)
+	defer os.Unsetenv("TFVM_MIRROR")
+
+	for spec, expected := range map[string]string{"1.1": "1.1.9", "1.10": "1.10.1", "~> 1.1.0": "1.1.9", "latest": "1.10.1"} {
+		if version, err := resolveVersion(spec); err != nil || version != expected {
+			t.Fatalf("resolved %q to %q (%v), expected %q", spec, version, err, expected)
+		}
+	}
+	if _, err := resolveVersion("1.2"); err == nil {
+		t.Fatalf("expected an error for a minor version with no releases")
+	}
+}
+
+// newMirror starts a release mirror serving a stub archive for each version on arch.
+// The published checksum is only correct for the first version.
+func newMirror(t *testing.T, arch string, versions ...string) *httptest.Server {
+	archive := stubArchive(t)
+	sum := sha256.Sum256(archive)
+
+	var index strings.Builder
+	for _, v := range versions {
+		fmt.Fprintf(&index, "<a href=\"/terraform/%s/\">terraform_%s</a>\n", v, v)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, index.String())
+			return
+		}
+
+		for i, v := range versions {
+			switch r.URL.Path {
+			case "/" + v + "/" + helper.ArchiveName(v, arch):
+				w.Write(archive)
+				return
+			case "/" + v + "/" + helper.SumsName(v):
+				if i == 0 {
+					fmt.Fprintf(w, "%x  %s\n", sum, helper.ArchiveName(v, arch))
+				} else {
+					fmt.Fprintf(w, "%x  %s\n", sha256.Sum256(nil), helper.ArchiveName(v, arch))
+				}
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+// stubArchive returns a release archive holding a stub binary and license.
+func stubArchive(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"terraform", "LICENSE.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("cannot create stub archive: %s", err)
+		}
+		w.Write([]byte("#!/bin/sh\n"))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+// stubVersion installs a stub binary of version into installDir and returns its path.
+func stubVersion(t *testing.T, installDir string, version string) string {
+	dir := helper.VersionDir(installDir, version)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("cannot create stub version directory: %s", err)
+	}
+
+	binary := helper.BinaryPath(installDir, "", version)
+	if err := ioutil.WriteFile(binary, nil, 0755); err != nil {
+		t.Fatalf("cannot create stub version file: %s", err)
+	}
+
+	if err := helper.WriteManifest(dir, helper.NewManifest(version, helper.SourceMirror)); err != nil {
+		t.Fatalf("cannot write stub manifest: %s", err)
+	}
+	return binary
+}
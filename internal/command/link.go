@@ -0,0 +1,111 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ehassett/tfvm/internal/helper"
+)
+
+// LinkCommand is a Command that registers an external Terraform binary under a custom name.
+type LinkCommand struct {
+	Meta
+}
+
+func (c *LinkCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	if len(args) != 2 {
+		err := errors.New("a name and the path to a Terraform binary are required")
+		c.Ui.Error(fmt.Sprintf("Could not link binary: %s", err))
+		return 1
+	}
+
+	target, err := linkVersion(c.InstallPath, c.Extension, args[0], args[1])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not link binary: %s", err))
+		return 1
+	}
+
+	result := linkJSON{Version: args[0], Path: target}
+	if c.outputJSON(result) {
+		return 0
+	}
+	c.Ui.Output(fmt.Sprintf("Terraform %s linked to %s. Run `tfvm use %s` to use it.", result.Version, result.Path, result.Version))
+	return 0
+}
+
+// linkJSON is the result of tfvm link with JSON output.
+type linkJSON struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+func (c *LinkCommand) Synopsis() string {
+	return "Register a custom Terraform binary"
+}
+
+func (c *LinkCommand) Help() string {
+	helpText := `
+Usage: tfvm link <name> <path>
+
+	Registers an external Terraform binary, such as one built from source, under a custom name.
+	Linked binaries can be selected with tfvm use like any installed version.
+	Running tfvm remove on a linked name unregisters it without deleting the binary.
+
+	Examples:
+		tfvm link dev ~/src/terraform/bin/terraform	Registers a local build as dev
+	`
+
+	return strings.TrimSpace(helpText)
+}
+
+// linkVersion registers the binary at binary under name in the install path, and returns its absolute path.
+func linkVersion(installPath string, extension string, name string, binary string) (string, error) {
+	if name == "" || name == "latest" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid name %q", name)
+	}
+
+	target, err := filepath.Abs(binary)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", binary)
+	}
+
+	// A linked version is a directory holding only its manifest, which points at the binary.
+	staging, err := helper.NewStagingDir(installPath)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest := helper.NewManifest(name, helper.SourceLinked)
+	manifest.Path = target
+	err = helper.WriteManifest(staging, manifest)
+	if err != nil {
+		return "", err
+	}
+
+	lock, err := helper.LockExclusive(installPath)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+
+	if _, err := os.Stat(helper.VersionDir(installPath, name)); err == nil {
+		return "", fmt.Errorf("%s is already installed or linked, run `tfvm remove %s` first", name, name)
+	}
+
+	return target, os.Rename(staging, helper.VersionDir(installPath, name))
+}
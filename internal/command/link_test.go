@@ -0,0 +1,132 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+// TestLink sets up the filesystem and Meta and tests linking a custom binary through its lifecycle.
+func TestLink(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-command-link")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	installDir, err := ioutil.TempDir(workDir, "versions")
+	if err != nil {
+		t.Fatalf("cannot create versions directory: %s", err)
+	}
+
+	binDir, err := ioutil.TempDir(workDir, "bin")
+	if err != nil {
+		t.Fatalf("cannot create bin directory: %s", err)
+	}
+
+	customBinName := workDir + string(filepath.Separator) + "terraform-dev"
+	customBin, err := os.Create(customBinName)
+	if err != nil {
+		t.Fatalf("cannot create stub custom binary: %s", err)
+	}
+
+	meta := func(ui cli.Ui) Meta {
+		return Meta{
+			TerraformVersion: "1.0.0",
+			InstallPath:      installDir,
+			BinPath:          binDir,
+			Extension:        "",
+			Ui:               ui,
+		}
+	}
+
+	// Link a custom binary and expect it to be registered.
+	t.Run("link custom binary", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &LinkCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"dev", customBin.Name()})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Lstat(installDir + string(filepath.Separator) + "dev"); os.IsNotExist(err) {
+			t.Fatalf("failed to register the custom binary\nstderr: %s", ui.ErrorWriter.String())
+		}
+	})
+
+	// Link the same name again and expect an error.
+	t.Run("link existing name", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &LinkCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"dev", customBin.Name()})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+	})
+
+	// Link a missing binary and expect an error.
+	t.Run("link missing binary", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &LinkCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"missing", workDir + string(filepath.Separator) + "missing"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+	})
+
+	// List versions and expect the linked binary to be shown as linked.
+	t.Run("list linked binary", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &ListCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.OutputWriter.String(), "dev (linked to "+customBin.Name()+")") {
+			t.Fatalf("expected the linked binary to be listed\nstdout: %s", ui.OutputWriter.String())
+		}
+	})
+
+	// Use the linked binary and expect it in the bin directory.
+	t.Run("use linked binary", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &UseCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"dev"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Stat(binDir + string(filepath.Separator) + "terraform"); os.IsNotExist(err) {
+			t.Fatalf("failed to use the linked binary\nstderr: %s", ui.ErrorWriter.String())
+		}
+	})
+
+	// Remove the linked binary and expect the original file to be kept.
+	t.Run("remove linked binary", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &RemoveCommand{Meta: meta(ui)}
+
+		status := c.Run([]string{"--yes", "dev"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Lstat(installDir + string(filepath.Separator) + "dev"); !os.IsNotExist(err) {
+			t.Fatalf("failed to unregister the custom binary\nstderr: %s", ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Stat(customBin.Name()); os.IsNotExist(err) {
+			t.Fatalf("unexpectedly removed the custom binary\nstderr: %s", ui.ErrorWriter.String())
+		}
+	})
+}
@@ -2,7 +2,9 @@ package command
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ehassett/tfvm/internal/helper"
 )
@@ -12,34 +14,386 @@ type ListCommand struct {
 	Meta
 }
 
+// listColumns are the columns --sort accepts, in the order they are shown by --long.
+var listColumns = []string{"version", "installed", "size", "source", "verified", "used"}
+
+// listJSON is the result of tfvm list with JSON output.
+type listJSON struct {
+	Versions []versionJSON `json:"versions"`
+}
+
+// versionJSON is an installed version in JSON output, described by its manifest.
+type versionJSON struct {
+	*helper.Manifest
+	Current bool  `json:"current"`
+	Size    int64 `json:"size"`
+}
+
+// versionDetails is an installed version with the details shown by --long.
+type versionDetails struct {
+	version  string
+	current  bool
+	manifest *helper.Manifest
+	size     int64
+}
+
 func (c *ListCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	var long, reverse, remote bool
+	var sortBy string
+	var filter versionFilter
+
+	cmdFlags := c.flagSet("list")
+	cmdFlags.BoolVar(&long, "long", false, "long")
+	cmdFlags.BoolVar(&long, "l", false, "long")
+	cmdFlags.StringVar(&sortBy, "sort", "version", "sort")
+	cmdFlags.BoolVar(&reverse, "reverse", false, "reverse")
+	cmdFlags.BoolVar(&remote, "remote", false, "remote")
+	cmdFlags.BoolVar(&helper.Refresh, "refresh", false, "refresh")
+	filter.addFlags(cmdFlags)
+	if err := cmdFlags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse arguments: %s", err))
+		return 1
+	}
+	if remote {
+		return c.listRemote(filter)
+	}
+	if !containsString(listColumns, sortBy) {
+		c.Ui.Error(fmt.Sprintf("Could not sort versions: unknown column %q, expected one of %s", sortBy, strings.Join(listColumns, ", ")))
+		return 1
+	}
+
+	lock, err := helper.LockShared(c.InstallPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not get installed versions: %s", err))
+		return 1
+	}
+	defer lock.Unlock()
+
 	versions, err := helper.GetInstalledVersions(c.InstallPath, c.Extension)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Could not get installed versions: %s", err))
 		return 1
 	}
 
-	for i := 0; i < len(versions); i++ {
-		if versions[i] == c.TerraformVersion {
-			c.Ui.Output(fmt.Sprintf("* %s", versions[i]))
+	details := make([]versionDetails, 0, len(versions))
+	for _, v := range versions {
+		d := versionDetails{version: v, current: v == c.TerraformVersion}
+		d.manifest, err = helper.ReadManifest(c.InstallPath, v)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Could not read manifest of %s: %s", v, err))
+			return 1
+		}
+		// A linked binary that has since been deleted is still listed, with no size.
+		d.size, _ = helper.VersionSize(c.InstallPath, c.Extension, v)
+		details = append(details, d)
+	}
+	sortDetails(details, sortBy, reverse)
+
+	details, err = filterDetails(details, filter)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not filter versions: %s", err))
+		return 1
+	}
+
+	result := listJSON{Versions: []versionJSON{}}
+	for _, d := range details {
+		result.Versions = append(result.Versions, versionJSON{Manifest: d.manifest, Current: d.current, Size: d.size})
+	}
+	if c.outputJSON(result) {
+		return 0
+	}
+
+	if long {
+		c.outputLong(details)
+		return 0
+	}
+
+	for _, d := range details {
+		line := d.version
+		if d.manifest.Source == helper.SourceLinked {
+			line += fmt.Sprintf(" (linked to %s)", d.manifest.Path)
+		}
+
+		if d.current {
+			c.Ui.Output(fmt.Sprintf("* %s", line))
 		} else {
-			c.Ui.Output(fmt.Sprintf("  %s", versions[i]))
+			c.Ui.Output(fmt.Sprintf("  %s", line))
 		}
 	}
 	return 0
 }
 
+// listRemote lists the available versions merged with the installed ones, newest first.
+func (c *ListCommand) listRemote(filter versionFilter) int {
+	available, err := helper.GetAvailableVersions()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not get available versions: %s", err))
+		return 1
+	}
+
+	lock, err := helper.LockShared(c.InstallPath)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not get installed versions: %s", err))
+		return 1
+	}
+	installed, err := helper.GetInstalledVersions(c.InstallPath, c.Extension)
+	lock.Unlock()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not get installed versions: %s", err))
+		return 1
+	}
+
+	merged := mergeVersions(available, installed)
+	versions, err := filter.apply(merged)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not filter versions: %s", err))
+		return 1
+	}
+
+	isAvailable, isInstalled := stringSet(available), stringSet(installed)
+	newest := newestInMinor(available)
+	result := remoteJSON{Versions: []remoteVersionJSON{}}
+	for _, v := range versions {
+		result.Versions = append(result.Versions, remoteVersionJSON{
+			Version:       v,
+			Available:     isAvailable[v],
+			Installed:     isInstalled[v],
+			Active:        v == c.TerraformVersion,
+			NewestInMinor: newest[v],
+		})
+	}
+	if c.outputJSON(result) {
+		return 0
+	}
+
+	for _, v := range result.Versions {
+		var tags []string
+		if v.Installed {
+			tags = append(tags, "installed")
+		}
+		if !v.Available {
+			tags = append(tags, "local only")
+		}
+		if v.NewestInMinor {
+			minor, _ := helper.MinorLine(v.Version)
+			tags = append(tags, "newest in "+minor)
+		}
+
+		marker := "  "
+		if v.Active {
+			marker = "* "
+		}
+		c.Ui.Output(strings.TrimRight(fmt.Sprintf("%s%-12s %s", marker, v.Version, strings.Join(tags, ", ")), " "))
+	}
+	return 0
+}
+
+// remoteJSON is the result of tfvm list --remote with JSON output.
+type remoteJSON struct {
+	Versions []remoteVersionJSON `json:"versions"`
+}
+
+// remoteVersionJSON is a version in the merged view of available and installed versions.
+type remoteVersionJSON struct {
+	Version       string `json:"version"`
+	Available     bool   `json:"available"`
+	Installed     bool   `json:"installed"`
+	Active        bool   `json:"active"`
+	NewestInMinor bool   `json:"newest_in_minor"`
+}
+
+// mergeVersions returns the versions in either list once each, newest first.
+func mergeVersions(a []string, b []string) []string {
+	seen := map[string]bool{}
+	var merged []string
+	for _, v := range append(append([]string{}, a...), b...) {
+		if !seen[v] {
+			seen[v] = true
+			merged = append(merged, v)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return helper.CompareVersions(merged[i], merged[j]) > 0
+	})
+	return merged
+}
+
+// newestInMinor returns the set of versions that are the newest of their minor line.
+func newestInMinor(versions []string) map[string]bool {
+	newest := map[string]string{}
+	for _, v := range versions {
+		minor, err := helper.MinorLine(v)
+		if err != nil {
+			continue
+		}
+		if current, ok := newest[minor]; !ok || helper.CompareVersions(v, current) > 0 {
+			newest[minor] = v
+		}
+	}
+
+	set := map[string]bool{}
+	for _, v := range newest {
+		set[v] = true
+	}
+	return set
+}
+
+// filterDetails returns the details of the versions that pass filter.
+func filterDetails(details []versionDetails, filter versionFilter) ([]versionDetails, error) {
+	names := make([]string, len(details))
+	for i, d := range details {
+		names[i] = d.version
+	}
+	kept, err := filter.apply(names)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := stringSet(kept)
+	filtered := []versionDetails{}
+	for _, d := range details {
+		if keep[d.version] {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered, nil
+}
+
+// outputLong prints details as a table with a column for each detail.
+func (c *ListCommand) outputLong(details []versionDetails) {
+	rows := [][]string{{"  VERSION", "INSTALLED", "SIZE", "SOURCE", "VERIFIED", "LAST USED"}}
+	for _, d := range details {
+		marker := "  "
+		if d.current {
+			marker = "* "
+		}
+
+		source := d.manifest.Source
+		if source == helper.SourceLinked {
+			source += " (" + d.manifest.Path + ")"
+		}
+
+		verified := "no"
+		if d.manifest.Verified {
+			verified = "yes"
+		}
+
+		lastUsed := "never"
+		if d.manifest.LastUsed != nil {
+			lastUsed = formatTime(*d.manifest.LastUsed)
+		}
+
+		rows = append(rows, []string{marker + d.version, formatTime(d.manifest.InstalledAt), formatBytes(d.size), source, verified, lastUsed})
+	}
+	outputTable(c.Ui, rows)
+}
+
 func (c *ListCommand) Synopsis() string {
 	return "List all installed versions of Terraform"
 }
 
 func (c *ListCommand) Help() string {
 	helpText := `
-Usage: tfvm list
+Usage: tfvm list [options]
 
 	Lists all installed Terraform versions.
 	The currently selected version will be indicated with *.
+	Binaries registered with tfvm link are shown with the path they are linked to.
+
+	With --remote, every available version is listed newest first together with the installed ones,
+	marking which are installed and which are the newest of their minor line.
+
+	Options:
+		-l, --long	Show the install date, disk size, source, verification status and last use of each version
+		--sort=<column>	Sort by version (default), installed, size, source, verified or used
+		--reverse	Reverse the sort order
+		--remote	Include the versions available to install
+		--refresh	Fetch the list of available versions again instead of using the cached copy
+		--major=<n>	Only list versions of a major version, such as 1
+		--minor=<n.n>	Only list versions of a minor version, such as 1.5
+		--since=<version>	Only list versions from a version on
+		--regex=<pattern>	Only list versions matching a regular expression
+		--limit=<n>	List at most n versions
+
+	Examples:
+		tfvm list --long --sort=used	Shows the least recently used versions first
+		tfvm list --remote --minor 1.5	Shows every v1.5.x release and which are installed
+		tfvm list --remote --since 1.3 --limit 10	Shows the ten newest releases from v1.3
 	`
 
 	return strings.TrimSpace(helpText)
 }
+
+// sortDetails sorts details by the named column, breaking ties by version.
+func sortDetails(details []versionDetails, column string, reverse bool) {
+	less := func(a, b versionDetails) bool {
+		switch column {
+		case "installed":
+			if !a.manifest.InstalledAt.Equal(b.manifest.InstalledAt) {
+				return a.manifest.InstalledAt.Before(b.manifest.InstalledAt)
+			}
+		case "size":
+			if a.size != b.size {
+				return a.size < b.size
+			}
+		case "source":
+			if a.manifest.Source != b.manifest.Source {
+				return a.manifest.Source < b.manifest.Source
+			}
+		case "verified":
+			if a.manifest.Verified != b.manifest.Verified {
+				return !a.manifest.Verified
+			}
+		case "used":
+			au, bu := lastUsed(a.manifest), lastUsed(b.manifest)
+			if !au.Equal(bu) {
+				return au.Before(bu)
+			}
+		}
+		return helper.CompareVersions(a.version, b.version) < 0
+	}
+
+	sort.SliceStable(details, func(i, j int) bool {
+		if reverse {
+			return less(details[j], details[i])
+		}
+		return less(details[i], details[j])
+	})
+}
+
+// lastUsed returns when the version of m was last used, or the zero time if it never was.
+func lastUsed(m *helper.Manifest) time.Time {
+	if m.LastUsed == nil {
+		return time.Time{}
+	}
+	return *m.LastUsed
+}
+
+// formatTime formats t in local time to the minute.
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// stringSet returns the set of values.
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// containsString reports whether s is one of values.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ehassett/tfvm/internal/helper"
+	"github.com/mitchellh/cli"
+)
+
+// TestList sets up the filesystem and Meta and tests various ListCommand cases.
+func TestList(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-command-list")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	installDir, err := ioutil.TempDir(workDir, "versions")
+	if err != nil {
+		t.Fatalf("cannot create versions directory: %s", err)
+	}
+
+	stubVersion(t, installDir, "1.0.0")
+	stubVersion(t, installDir, "0.15.0")
+	if err := helper.RecordUse(installDir, "1.0.0"); err != nil {
+		t.Fatalf("cannot record use: %s", err)
+	}
+
+	listTestCase := func(test func(t *testing.T, c *ListCommand, ui *cli.MockUi)) func(t *testing.T) {
+		return func(t *testing.T) {
+			ui := new(cli.MockUi)
+
+			c := &ListCommand{
+				Meta: Meta{
+					TerraformVersion: "1.0.0",
+					InstallPath:      installDir,
+					Extension:        "",
+					Ui:               ui,
+				},
+			}
+
+			test(t, c, ui)
+		}
+	}
+
+	// List versions and expect them oldest first with the current version marked.
+	t.Run("list versions", listTestCase(func(t *testing.T, c *ListCommand, ui *cli.MockUi) {
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if ui.OutputWriter.String() != "  0.15.0\n* 1.0.0\n" {
+			t.Fatalf("unexpected output\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// List versions with details sorted by last use, most recent first.
+	t.Run("list long sorted by use", listTestCase(func(t *testing.T, c *ListCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--long", "--sort=used", "--reverse"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		lines := strings.Split(strings.TrimSpace(ui.OutputWriter.String()), "\n")
+		if len(lines) != 3 || !strings.Contains(lines[0], "LAST USED") {
+			t.Fatalf("expected a header and a line per version\nstdout: %s", ui.OutputWriter.String())
+		}
+		if !strings.HasPrefix(lines[1], "* 1.0.0") || !strings.Contains(lines[1], "mirror") || !strings.HasSuffix(lines[2], "never") {
+			t.Fatalf("expected the used version first with its details\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// Sort by an unknown column and expect an error.
+	t.Run("unknown sort column", listTestCase(func(t *testing.T, c *ListCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--sort=name"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+	}))
+
+	// List versions as JSON and expect a document describing each version.
+	t.Run("list versions as json", listTestCase(func(t *testing.T, c *ListCommand, ui *cli.MockUi) {
+		c.Ui = NewJSONUi(ui)
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstdout: %s", status, ui.OutputWriter.String())
+		}
+
+		var result struct {
+			Versions []struct {
+				Version string `json:"version"`
+				Source  string `json:"source"`
+				Current bool   `json:"current"`
+			} `json:"versions"`
+		}
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil {
+			t.Fatalf("cannot decode output: %s\nstdout: %s", err, ui.OutputWriter.String())
+		}
+		if len(result.Versions) != 2 || result.Versions[1].Version != "1.0.0" || !result.Versions[1].Current || result.Versions[1].Source != helper.SourceMirror {
+			t.Fatalf("unexpected versions %+v", result.Versions)
+		}
+	}))
+
+	// Sort by an unknown column with JSON output and expect a structured error.
+	t.Run("error as json", listTestCase(func(t *testing.T, c *ListCommand, ui *cli.MockUi) {
+		c.Ui = NewJSONUi(ui)
+		status := c.Run([]string{"--sort=name"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstdout: %s", status, ui.OutputWriter.String())
+		}
+
+		var result errorJSON
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil || !strings.HasPrefix(result.Error.Message, "Could not sort versions") {
+			t.Fatalf("expected a structured error\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// List the versions available from a mirror merged with the installed ones.
+	t.Run("list remote versions", listTestCase(func(t *testing.T, c *ListCommand, ui *cli.MockUi) {
+		server := newMirror(t, "linux_amd64", "1.5.7", "1.5.6", "1.4.2")
+		defer server.Close()
+		os.Setenv("TFVM_MIRROR", server.URL)
+		defer os.Unsetenv("TFVM_MIRROR")
+
+		status := c.Run([]string{"--remote", "--since", "1.0"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		expected := "  1.5.7        newest in 1.5\n  1.5.6\n  1.4.2        newest in 1.4\n* 1.0.0        installed, local only\n"
+		if ui.OutputWriter.String() != expected {
+			t.Fatalf("unexpected output\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// Filter the merged view and expect only the matching versions.
+	t.Run("filter remote versions", listTestCase(func(t *testing.T, c *ListCommand, ui *cli.MockUi) {
+		server := newMirror(t, "linux_amd64", "1.5.7", "1.5.6", "1.4.2")
+		defer server.Close()
+		os.Setenv("TFVM_MIRROR", server.URL)
+		defer os.Unsetenv("TFVM_MIRROR")
+
+		status := c.Run([]string{"--remote", "--minor", "1.5", "--limit", "1"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if ui.OutputWriter.String() != "  1.5.7        newest in 1.5\n" {
+			t.Fatalf("unexpected output\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// Filter with an invalid minor version and expect an error.
+	t.Run("invalid filter", listTestCase(func(t *testing.T, c *ListCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--minor", "1"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+	}))
+}
+
+// TestListWithoutStore tests that Meta is initialized on first use and that listing does not create the store.
+func TestListWithoutStore(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-command-list-init")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	installDir := workDir + string(filepath.Separator) + "versions"
+
+	// List with a store that does not exist yet and expect nothing to be listed or created.
+	t.Run("no store", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &ListCommand{
+			Meta: Meta{
+				Ui: ui,
+				Init: func(m *Meta) error {
+					m.InstallPath = installDir
+					m.BinPath = workDir + string(filepath.Separator) + "bin"
+					return nil
+				},
+			},
+		}
+
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Stat(installDir); !os.IsNotExist(err) {
+			t.Fatalf("unexpectedly created the store")
+		}
+	})
+
+	// Fail to initialize and expect the error to be reported by the command.
+	t.Run("initialization error", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		c := &ListCommand{
+			Meta: Meta{
+				Ui: ui,
+				Init: func(m *Meta) error {
+					return errors.New("no home directory")
+				},
+			},
+		}
+
+		status := c.Run([]string{})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d", status)
+		}
+
+		if !strings.Contains(ui.ErrorWriter.String(), "no home directory") {
+			t.Fatalf("expected the initialization error\nstderr: %s", ui.ErrorWriter.String())
+		}
+	})
+}
@@ -1,13 +1,46 @@
 package command
 
-import "github.com/mitchellh/cli"
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mitchellh/cli"
+)
 
 // Meta is a struct that contains necessary metadata used by commands.
 type Meta struct {
 	TerraformVersion string
 	InstallPath      string
 	BinPath          string
-	TempPath         string
 	Extension        string
-	Ui 							 cli.Ui
+	Ui               cli.Ui
+
+	// Init fills in the other fields the first time a command needs them, so that help and
+	// commands that do not use the store never touch the filesystem. It is nil when they are set directly.
+	Init func(*Meta) error
+}
+
+// initialize runs Init if it has not run yet, and reports whether the command can go on.
+func (m *Meta) initialize() bool {
+	if m.Init == nil {
+		return true
+	}
+
+	init := m.Init
+	m.Init = nil
+	if err := init(m); err != nil {
+		m.Ui.Error(fmt.Sprintf("Failed to initialize tfvm: %s", err))
+		return false
+	}
+	return true
+}
+
+// flagSet returns a FlagSet for the named command.
+// Parse errors are returned to the caller rather than printed.
+func (m *Meta) flagSet(name string) *flag.FlagSet {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.SetOutput(io.Discard)
+	f.Usage = func() {}
+	return f
 }
@@ -0,0 +1,205 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ehassett/tfvm/internal/helper"
+)
+
+// OutdatedCommand is a Command that reports installed versions with newer releases.
+type OutdatedCommand struct {
+	Meta
+}
+
+// outdatedVersion is an installed, active or pinned version and the newer releases available for it.
+type outdatedVersion struct {
+	Version   string `json:"version"`
+	Line      string `json:"line"`
+	Installed bool   `json:"installed"`
+	Active    bool   `json:"active"`
+	Pinned    bool   `json:"pinned"`
+	Patch     string `json:"patch,omitempty"`
+	Minor     string `json:"minor,omitempty"`
+}
+
+// outdatedJSON is the result of tfvm outdated with JSON output.
+type outdatedJSON struct {
+	Versions []outdatedVersion `json:"versions"`
+}
+
+func (c *OutdatedCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	cmdFlags := c.flagSet("outdated")
+	cmdFlags.BoolVar(&helper.Refresh, "refresh", false, "refresh")
+	if err := cmdFlags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse arguments: %s", err))
+		return 1
+	}
+
+	versions, err := findOutdated(c.TerraformVersion, c.InstallPath, c.Extension)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not check for newer versions: %s", err))
+		return 1
+	}
+	if c.outputJSON(outdatedJSON{Versions: versions}) {
+		return 0
+	}
+
+	rows := [][]string{{"  VERSION", "PATCH", "MINOR", ""}}
+	var outdated int
+	for _, v := range versions {
+		if v.Patch != "" || v.Minor != "" {
+			outdated++
+		}
+
+		marker := "  "
+		if v.Active {
+			marker = "* "
+		}
+
+		var notes []string
+		if v.Pinned {
+			notes = append(notes, "pinned by .tfversion")
+		}
+		if !v.Installed {
+			notes = append(notes, "not installed")
+		}
+		rows = append(rows, []string{marker + v.Version, orDash(v.Patch), orDash(v.Minor), strings.Join(notes, ", ")})
+	}
+
+	if outdated == 0 {
+		c.Ui.Output("All installed versions are up to date.")
+		return 0
+	}
+	outputTable(c.Ui, rows)
+	return 0
+}
+
+func (c *OutdatedCommand) Synopsis() string {
+	return "Show installed versions of Terraform with newer releases"
+}
+
+func (c *OutdatedCommand) Help() string {
+	helpText := `
+Usage: tfvm outdated [options]
+
+	Reports, for the newest installed version of each minor line and for the active and pinned versions,
+	the newest patch release of its minor line and the newest release of a later minor line, if either is newer.
+	The pinned versions are those matching .tfversion in the current directory, which may give a full version,
+	a minor version or a constraint.
+
+	Options:
+		--refresh	Fetch the list of available versions again instead of using the cached copy
+
+	To install the newer releases, run:
+		tfvm upgrade
+	`
+
+	return strings.TrimSpace(helpText)
+}
+
+// findOutdated returns the newest installed version of each minor line, together with the active version
+// and the versions matching the pin in .tfversion, and the newer releases available for each, oldest version first.
+func findOutdated(currentVersion string, installPath string, extension string) ([]outdatedVersion, error) {
+	available, err := helper.GetAvailableVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := helper.LockShared(installPath)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := helper.GetInstalledVersions(installPath, extension)
+	lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := getProjectVersion()
+	if err != nil {
+		return nil, fmt.Errorf("could not read .tfversion: %w", err)
+	}
+
+	// Installed versions are sorted oldest first, so the last of each line is its newest.
+	rows := map[string]*outdatedVersion{}
+	newest := map[string]string{}
+	for _, v := range installed {
+		if _, linked := helper.LinkedVersion(installPath, extension, v); linked {
+			continue
+		}
+		line, err := helper.MinorLine(v)
+		if err != nil {
+			continue
+		}
+		newest[line] = v
+	}
+	for _, v := range newest {
+		rows[v] = &outdatedVersion{Version: v, Installed: true}
+	}
+
+	// A minor version or constraint in .tfversion pins every installed version that matches it.
+	var pinnedVersions []string
+	if pinned != "" {
+		pinnedVersions = matchingVersions([]string{pinned}, installed)
+	}
+	pinnedSet := stringSet(pinnedVersions)
+
+	installedSet := stringSet(installed)
+	for _, v := range append([]string{currentVersion}, pinnedVersions...) {
+		if _, ok := rows[v]; ok || strings.Count(v, ".") != 2 {
+			continue
+		}
+		if _, err := helper.MinorLine(v); err == nil {
+			rows[v] = &outdatedVersion{Version: v, Installed: installedSet[v]}
+		}
+	}
+
+	versions := []outdatedVersion{}
+	for v, row := range rows {
+		row.Line, _ = helper.MinorLine(v)
+		row.Active = v == currentVersion
+		row.Pinned = pinnedSet[v]
+		row.Patch, row.Minor = newerReleases(v, available)
+		versions = append(versions, *row)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return helper.CompareVersions(versions[i].Version, versions[j].Version) < 0
+	})
+	return versions, nil
+}
+
+// newerReleases returns the newest available release of the minor line of version if it is newer,
+// and the newest available release of a later minor line of the same major version, if any.
+func newerReleases(version string, available []string) (string, string) {
+	line, err := helper.MinorLine(version)
+	if err != nil {
+		return "", ""
+	}
+
+	var patch, minor string
+	sameLine, _ := helper.ParseConstraint("~> " + line + ".0")
+	sameMajor, _ := helper.ParseConstraint("~> " + line)
+	for _, v := range available {
+		if patch == "" && sameLine.Check(v) && helper.CompareVersions(v, version) > 0 {
+			patch = v
+		}
+		if minor == "" && sameMajor.Check(v) && !sameLine.Check(v) {
+			minor = v
+		}
+	}
+	return patch, minor
+}
+
+// orDash returns s, or a dash if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+// TestOutdated serves releases from a local mirror and tests various OutdatedCommand cases.
+func TestOutdated(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-command-outdated")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	installDir, err := ioutil.TempDir(workDir, "versions")
+	if err != nil {
+		t.Fatalf("cannot create versions directory: %s", err)
+	}
+
+	stubVersion(t, installDir, "1.5.5")
+	stubVersion(t, installDir, "1.5.6")
+	stubVersion(t, installDir, "1.6.1")
+
+	server := newMirror(t, "linux_amd64", "1.6.1", "1.6.0", "1.5.7", "1.5.6", "1.5.5")
+	defer server.Close()
+	os.Setenv("TFVM_MIRROR", server.URL)
+	defer os.Unsetenv("TFVM_MIRROR")
+
+	outdatedTestCase := func(test func(t *testing.T, c *OutdatedCommand, ui *cli.MockUi)) func(t *testing.T) {
+		return func(t *testing.T) {
+			ui := new(cli.MockUi)
+
+			c := &OutdatedCommand{
+				Meta: Meta{
+					TerraformVersion: "1.5.5",
+					InstallPath:      installDir,
+					Extension:        "",
+					Ui:               ui,
+				},
+			}
+
+			test(t, c, ui)
+		}
+	}
+
+	// Check for newer releases and expect the active version and the newest of each line.
+	t.Run("outdated versions", outdatedTestCase(func(t *testing.T, c *OutdatedCommand, ui *cli.MockUi) {
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		expected := "  VERSION  PATCH  MINOR\n* 1.5.5    1.5.7  1.6.1\n  1.5.6    1.5.7  1.6.1\n  1.6.1    -      -\n"
+		if ui.OutputWriter.String() != expected {
+			t.Fatalf("unexpected output\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// Check for newer releases as JSON.
+	t.Run("outdated versions as json", outdatedTestCase(func(t *testing.T, c *OutdatedCommand, ui *cli.MockUi) {
+		c.Ui = NewJSONUi(ui)
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstdout: %s", status, ui.OutputWriter.String())
+		}
+
+		var result outdatedJSON
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil {
+			t.Fatalf("cannot decode output: %s\nstdout: %s", err, ui.OutputWriter.String())
+		}
+		if len(result.Versions) != 3 || !result.Versions[0].Active || result.Versions[0].Patch != "1.5.7" || result.Versions[2].Minor != "" {
+			t.Fatalf("unexpected versions %+v", result.Versions)
+		}
+	}))
+
+	// Pin a minor version in .tfversion and expect the installed versions of that line to be reported as pinned.
+	t.Run("pinned minor version", outdatedTestCase(func(t *testing.T, c *OutdatedCommand, ui *cli.MockUi) {
+		if err := ioutil.WriteFile(".tfversion", []byte("1.5\n"), 0644); err != nil {
+			t.Fatalf("cannot write stub .tfversion file: %s", err)
+		}
+		defer os.Remove(".tfversion")
+
+		c.Ui = NewJSONUi(ui)
+		status := c.Run([]string{})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstdout: %s", status, ui.OutputWriter.String())
+		}
+
+		var result outdatedJSON
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil {
+			t.Fatalf("cannot decode output: %s\nstdout: %s", err, ui.OutputWriter.String())
+		}
+		if len(result.Versions) != 3 || !result.Versions[0].Pinned || !result.Versions[1].Pinned || result.Versions[2].Pinned {
+			t.Fatalf("expected 1.5.5 and 1.5.6 to be pinned, got %+v", result.Versions)
+		}
+	}))
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mitchellh/cli"
+)
+
+// errJSONInput is returned when a command would need to prompt while writing JSON.
+var errJSONInput = errors.New("cannot prompt for input with JSON output")
+
+// errorJSON is the document written in place of an error message when JSON output is requested.
+type errorJSON struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// jsonUi is the Ui of commands run with --json. Each command writes a single JSON document
+// describing its result to stdout, errors are written there as error documents, and the
+// text messages meant for people are dropped.
+type jsonUi struct {
+	cli.Ui
+}
+
+// NewJSONUi returns a Ui that writes JSON documents through ui in place of text messages.
+func NewJSONUi(ui cli.Ui) cli.Ui {
+	return &jsonUi{ui}
+}
+
+func (u *jsonUi) Ask(query string) (string, error) {
+	return "", errJSONInput
+}
+
+func (u *jsonUi) AskSecret(query string) (string, error) {
+	return "", errJSONInput
+}
+
+func (u *jsonUi) Output(msg string) {}
+
+func (u *jsonUi) Info(msg string) {}
+
+func (u *jsonUi) Warn(msg string) {
+	// Warnings stay readable on stderr without breaking the document on stdout.
+	u.Ui.Error(msg)
+}
+
+func (u *jsonUi) Error(msg string) {
+	var doc errorJSON
+	doc.Error.Message = msg
+	u.emit(doc)
+}
+
+// emit writes v to stdout as an indented JSON document.
+func (u *jsonUi) emit(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		u.Ui.Error(err.Error())
+		return
+	}
+	u.Ui.Output(string(out))
+}
+
+// outputJSON writes v as the result of the command if JSON output was requested,
+// and reports whether it did so the caller can skip its text output.
+func (m *Meta) outputJSON(v interface{}) bool {
+	ui, ok := m.Ui.(*jsonUi)
+	if !ok {
+		return false
+	}
+	ui.emit(v)
+	return true
+}
+
+// outputTable prints rows through ui with their columns aligned.
+func outputTable(ui cli.Ui, rows [][]string) {
+	var buf strings.Builder
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		ui.Output(strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,123 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ehassett/tfvm/internal/helper"
+	"github.com/mitchellh/cli"
+)
+
+const (
+	// ttyProgressInterval is how often progress is redrawn on a terminal.
+	ttyProgressInterval = 100 * time.Millisecond
+
+	// logProgressInterval is how often progress is logged when not on a terminal.
+	logProgressInterval = 10 * time.Second
+)
+
+// ProgressUi is a Ui that can redraw a single line in place, such as on a terminal.
+type ProgressUi interface {
+	cli.Ui
+	Progress(msg string)
+}
+
+// lineUi hides the redrawing of a ProgressUi, so that progress is reported line by line.
+type lineUi struct {
+	cli.Ui
+}
+
+// progressFactory returns the ProgressFunc for a download named label, which may be nil.
+type progressFactory func(label string) helper.ProgressFunc
+
+// newProgress returns a progressFactory that reports downloads through ui.
+// A ProgressUi redraws a line in place, any other Ui logs a line periodically,
+// and nothing is reported when quiet is set.
+func newProgress(ui cli.Ui, quiet bool) progressFactory {
+	return func(label string) helper.ProgressFunc {
+		if quiet {
+			return nil
+		}
+		return reportProgress(ui, label)
+	}
+}
+
+// reportProgress returns a ProgressFunc that reports the download named label through ui.
+func reportProgress(ui cli.Ui, label string) helper.ProgressFunc {
+	pui, tty := ui.(ProgressUi)
+	interval := logProgressInterval
+	if tty {
+		interval = ttyProgressInterval
+	}
+
+	start := time.Now()
+	var last time.Time
+	return func(done int64, total int64) {
+		now := time.Now()
+		finished := total >= 0 && done >= total
+		if !finished && now.Sub(last) < interval {
+			return
+		}
+		last = now
+
+		msg := formatProgress(label, done, total, now.Sub(start))
+		if !tty {
+			logProgress(ui, msg)
+			return
+		}
+		if finished {
+			msg += "\n"
+		}
+		pui.Progress(msg)
+	}
+}
+
+// logProgress writes a line of progress to stderr through ui, so that it is not mixed with the output
+// of the command in logs and pipes. Progress is not shown with JSON output.
+func logProgress(ui cli.Ui, msg string) {
+	switch u := ui.(type) {
+	case lineUi:
+		logProgress(u.Ui, msg)
+	case *jsonUi:
+	default:
+		ui.Error(msg)
+	}
+}
+
+// formatProgress describes a download's size, percentage, rate and remaining time.
+func formatProgress(label string, done int64, total int64, elapsed time.Duration) string {
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(done) / elapsed.Seconds()
+	}
+
+	if total < 0 {
+		return fmt.Sprintf("Downloading %s: %s (%s/s)", label, formatBytes(done), formatBytes(int64(rate)))
+	}
+
+	percent := 100
+	if total > 0 {
+		percent = int(done * 100 / total)
+	}
+	msg := fmt.Sprintf("Downloading %s: %s / %s (%d%%), %s/s", label, formatBytes(done), formatBytes(total), percent, formatBytes(int64(rate)))
+	if done < total && rate > 0 {
+		eta := time.Duration(float64(total-done) / rate * float64(time.Second))
+		msg += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+	}
+	return msg
+}
+
+// formatBytes formats a size in bytes using binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
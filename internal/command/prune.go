@@ -0,0 +1,276 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ehassett/tfvm/internal/helper"
+)
+
+// PruneCommand is a Command that removes versions that are no longer used.
+type PruneCommand struct {
+	Meta
+}
+
+// pruneSkipDirs are directories that are not searched for version files.
+var pruneSkipDirs = map[string]bool{".git": true, ".terraform": true, "node_modules": true}
+
+// stringList is a flag that can be given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (c *PruneCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	var days int
+	var roots stringList
+	var keepMinor, yes, dryRun bool
+
+	cmdFlags := c.flagSet("prune")
+	cmdFlags.IntVar(&days, "days", defaultPruneDays(), "days")
+	cmdFlags.Var(&roots, "root", "root")
+	cmdFlags.BoolVar(&keepMinor, "keep-minor", helper.ConfigBool("prune_keep_minor"), "keep-minor")
+	cmdFlags.BoolVar(&yes, "yes", false, "yes")
+	cmdFlags.BoolVar(&yes, "y", false, "yes")
+	cmdFlags.BoolVar(&dryRun, "dry-run", false, "dry-run")
+	if err := cmdFlags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse arguments: %s", err))
+		return 1
+	}
+	if len(roots) == 0 {
+		roots = filepath.SplitList(helper.ConfigValue("project_roots"))
+	}
+
+	referenced, err := c.referencedVersions(roots)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not find version files: %s", err))
+		return 1
+	}
+
+	candidates, err := c.pruneCandidates(time.Duration(days)*24*time.Hour, referenced, keepMinor)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not find unused versions: %s", err))
+		return 1
+	}
+	if len(candidates) == 0 {
+		if !c.outputJSON(removeJSON{Removed: []removedJSON{}}) {
+			c.Ui.Output(fmt.Sprintf("No versions are unused for %d days.", days))
+		}
+		return 0
+	}
+
+	planned := make([]removedJSON, len(candidates))
+	for i, candidate := range candidates {
+		planned[i].Version = candidate.version
+	}
+
+	if dryRun && c.outputJSON(removeJSON{Removed: planned}) {
+		return 0
+	}
+	if dryRun || !yes {
+		c.Ui.Output("The following versions will be removed:")
+		for _, candidate := range candidates {
+			c.Ui.Output(fmt.Sprintf("  %s (%s)", candidate.version, candidate.reason))
+		}
+	}
+	if dryRun {
+		return 0
+	}
+
+	if !yes && !c.confirmRemoval(len(planned)) {
+		return 1
+	}
+	return c.removeVersions(planned)
+}
+
+// defaultPruneDays returns the days a version must be unused before it is pruned, which is prune_days if set.
+func defaultPruneDays() int {
+	if days, err := strconv.Atoi(helper.ConfigValue("prune_days")); err == nil {
+		return days
+	}
+	return 90
+}
+
+// pruneCandidate is a version that would be pruned, with why it is considered unused.
+type pruneCandidate struct {
+	version string
+	reason  string
+}
+
+// pruneCandidates returns the installed versions that have not been used within maxAge and are not
+// referenced, oldest first. The active version and linked binaries are never pruned, nor is the
+// newest version of each minor line when keepMinor is set.
+func (c *PruneCommand) pruneCandidates(maxAge time.Duration, referenced []string, keepMinor bool) ([]pruneCandidate, error) {
+	lock, err := helper.LockShared(c.InstallPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	installed, err := helper.GetInstalledVersions(c.InstallPath, c.Extension)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := map[string]bool{c.TerraformVersion: true}
+	for _, v := range matchingVersions(referenced, installed) {
+		keep[v] = true
+	}
+	if keepMinor {
+		newest := map[string]string{}
+		for _, v := range installed {
+			if line, err := helper.MinorLine(v); err == nil {
+				newest[line] = v
+			}
+		}
+		for _, v := range newest {
+			keep[v] = true
+		}
+	}
+
+	var candidates []pruneCandidate
+	for _, v := range installed {
+		if keep[v] {
+			continue
+		}
+
+		m, err := helper.ReadManifest(c.InstallPath, v)
+		if err != nil {
+			return nil, err
+		}
+		if m.Source == helper.SourceLinked {
+			continue
+		}
+
+		// A version that was never used counts from when it was installed.
+		reason := "never used, installed %d days ago"
+		since := m.InstalledAt
+		if m.LastUsed != nil {
+			reason = "last used %d days ago"
+			since = *m.LastUsed
+		}
+		age := time.Since(since)
+		if age < maxAge {
+			continue
+		}
+		candidates = append(candidates, pruneCandidate{version: v, reason: fmt.Sprintf(reason, int(age.Hours()/24))})
+	}
+	return candidates, nil
+}
+
+// referencedVersions returns the versions pinned by the .tfversion files under roots and in the working directory.
+// Roots that do not exist are skipped with a warning, so that one stale path does not stop the others being searched.
+func (m *Meta) referencedVersions(roots []string) ([]string, error) {
+	var specs []string
+	if pinned, err := getProjectVersion(); err == nil && pinned != "" {
+		specs = append(specs, pinned)
+	}
+
+	for _, root := range roots {
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			m.Ui.Warn(fmt.Sprintf("Skipping project root %s, which does not exist.", root))
+			continue
+		}
+
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable directories cannot reference anything we can see.
+				if d != nil && d.IsDir() && path != root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() && pruneSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			if d.IsDir() || d.Name() != ".tfversion" {
+				return nil
+			}
+
+			if spec, err := readVersionFile(path); err == nil {
+				specs = append(specs, strings.TrimSpace(spec))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not search %s: %w", root, err)
+		}
+	}
+	return specs, nil
+}
+
+// matchingVersions returns the installed versions that version specifications could refer to.
+// A minor version or constraint refers to every installed version it matches, and latest to the newest one.
+func matchingVersions(specs []string, installed []string) []string {
+	var matches []string
+	for _, spec := range specs {
+		switch {
+		case spec == "latest":
+			for i := len(installed) - 1; i >= 0; i-- {
+				if _, err := helper.MinorLine(installed[i]); err == nil {
+					matches = append(matches, installed[i])
+					break
+				}
+			}
+			continue
+		case strings.Count(spec, ".") == 1 && !helper.IsConstraint(spec):
+			spec = "~> " + spec + ".0"
+		case !helper.IsConstraint(spec):
+			matches = append(matches, spec)
+			continue
+		}
+
+		constraint, err := helper.ParseConstraint(spec)
+		if err != nil {
+			continue
+		}
+		for _, v := range installed {
+			if constraint.Check(v) {
+				matches = append(matches, v)
+			}
+		}
+	}
+	return matches
+}
+
+func (c *PruneCommand) Synopsis() string {
+	return "Remove versions of Terraform that are no longer used"
+}
+
+func (c *PruneCommand) Help() string {
+	helpText := `
+Usage: tfvm prune [options]
+
+	Removes the installed versions that have not been used for a number of days,
+	counting from when they were installed for versions that were never used.
+	Versions pinned by a .tfversion file in the current directory or under a project root are kept,
+	as are the active version and binaries registered with tfvm link.
+	Project roots are given with --root or as a list of paths in the project_roots setting.
+
+	Options:
+		--days=<n>	Remove versions not used for n days (default prune_days, or 90)
+		--root=<path>	Search path for .tfversion files, can be given several times
+		--keep-minor	Keep the newest version of each minor line, as if prune_keep_minor were set
+		--yes, -y	Do not ask for confirmation
+		--dry-run	Only show the versions that would be removed
+
+	Examples:
+		tfvm prune --days 30 --root ~/src	Removes versions unused for 30 days that no project in ~/src pins
+	`
+
+	return strings.TrimSpace(helpText)
+}
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ehassett/tfvm/internal/helper"
+	"github.com/mitchellh/cli"
+)
+
+// TestPrune sets up the filesystem and Meta and tests various PruneCommand cases.
+func TestPrune(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-command-prune")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	installDir, err := ioutil.TempDir(workDir, "versions")
+	if err != nil {
+		t.Fatalf("cannot create versions directory: %s", err)
+	}
+
+	binDir, err := ioutil.TempDir(workDir, "bin")
+	if err != nil {
+		t.Fatalf("cannot create bin directory: %s", err)
+	}
+
+	// Every version but 1.6.0 was last used 100 days ago.
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	for _, v := range []string{"1.4.0", "1.5.0", "1.5.1", "1.5.2", "1.6.0"} {
+		stubVersion(t, installDir, v)
+		if v == "1.6.0" {
+			continue
+		}
+		m, _ := helper.ReadManifest(installDir, v)
+		m.InstalledAt = old
+		m.LastUsed = &old
+		if err := helper.WriteManifest(helper.VersionDir(installDir, v), m); err != nil {
+			t.Fatalf("cannot write stub manifest: %s", err)
+		}
+	}
+
+	// A project pins 1.4.0.
+	projectDir := workDir + string(filepath.Separator) + "projects" + string(filepath.Separator) + "network"
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("cannot create project directory: %s", err)
+	}
+	if err := ioutil.WriteFile(projectDir+string(filepath.Separator)+".tfversion", []byte("1.4.0\n"), 0644); err != nil {
+		t.Fatalf("cannot write stub .tfversion file: %s", err)
+	}
+	root := workDir + string(filepath.Separator) + "projects"
+
+	pruneTestCase := func(test func(t *testing.T, c *PruneCommand, ui *cli.MockUi)) func(t *testing.T) {
+		return func(t *testing.T) {
+			ui := new(cli.MockUi)
+
+			c := &PruneCommand{
+				Meta: Meta{
+					TerraformVersion: "1.5.0",
+					InstallPath:      installDir,
+					BinPath:          binDir,
+					Extension:        "",
+					Ui:               ui,
+				},
+			}
+
+			test(t, c, ui)
+		}
+	}
+
+	// Preview the prune and expect unused, unpinned versions other than the active one.
+	t.Run("dry run", pruneTestCase(func(t *testing.T, c *PruneCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--dry-run", "--days", "30", "--root", root})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		expected := "The following versions will be removed:\n  1.5.1 (last used 100 days ago)\n  1.5.2 (last used 100 days ago)\n"
+		if ui.OutputWriter.String() != expected {
+			t.Fatalf("unexpected preview\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// Prune keeping the newest of each minor line and expect only 1.5.1 to be removed.
+	t.Run("keep newest of each minor line", pruneTestCase(func(t *testing.T, c *PruneCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--yes", "--keep-minor", "--days", "30", "--root", root})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		versions, _ := helper.GetInstalledVersions(installDir, "")
+		if !reflect.DeepEqual(versions, []string{"1.4.0", "1.5.0", "1.5.2", "1.6.0"}) {
+			t.Fatalf("expected 1.5.1 to be removed, %v remain", versions)
+		}
+	}))
+
+	// Switch away from the active version and expect it not to be pruned, since it was in use until now.
+	t.Run("previously active version", pruneTestCase(func(t *testing.T, c *PruneCommand, ui *cli.MockUi) {
+		if err := useVersion(c.TerraformVersion, installDir, binDir, "", "1.6.0"); err != nil {
+			t.Fatalf("cannot switch versions: %s", err)
+		}
+		c.TerraformVersion = "1.6.0"
+
+		status := c.Run([]string{"--dry-run", "--days", "30", "--root", root})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		expected := "The following versions will be removed:\n  1.5.2 (last used 100 days ago)\n"
+		if ui.OutputWriter.String() != expected {
+			t.Fatalf("unexpected preview\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// Prune with a longer period and expect nothing to be removed.
+	t.Run("nothing unused", pruneTestCase(func(t *testing.T, c *PruneCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--yes", "--days", "365"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if ui.OutputWriter.String() != "No versions are unused for 365 days.\n" {
+			t.Fatalf("unexpected output\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+
+	// Search a missing project root next to an existing one and expect it to be skipped with a warning.
+	t.Run("missing root", pruneTestCase(func(t *testing.T, c *PruneCommand, ui *cli.MockUi) {
+		missing := workDir + string(filepath.Separator) + "missing"
+		status := c.Run([]string{"--dry-run", "--days", "30", "--root", missing, "--root", root})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.ErrorWriter.String(), "Skipping project root "+missing) {
+			t.Fatalf("expected a warning about the missing root\nstderr: %s", ui.ErrorWriter.String())
+		}
+		if strings.Contains(ui.OutputWriter.String(), "1.4.0") {
+			t.Fatalf("expected the version pinned under the other root to be kept\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+}
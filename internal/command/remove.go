@@ -16,38 +16,239 @@ type RemoveCommand struct {
 }
 
 func (c *RemoveCommand) Run(args []string) int {
-	if len(args) < 1 {
+	if !c.initialize() {
+		return 1
+	}
+
+	var all, allExceptActive, yes, force, dryRun bool
+
+	cmdFlags := c.flagSet("remove")
+	cmdFlags.BoolVar(&all, "all", false, "all")
+	cmdFlags.BoolVar(&allExceptActive, "all-except-active", false, "all-except-active")
+	cmdFlags.BoolVar(&yes, "yes", false, "yes")
+	cmdFlags.BoolVar(&yes, "y", false, "yes")
+	cmdFlags.BoolVar(&force, "force", false, "force")
+	cmdFlags.BoolVar(&dryRun, "dry-run", false, "dry-run")
+	if err := cmdFlags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse arguments: %s", err))
+		return 1
+	}
+	args = cmdFlags.Args()
+
+	if len(args) < 1 && !all && !allExceptActive {
 		err := errors.New("no version specified")
 		c.Ui.Error(fmt.Sprintf("Could not remove version: %s", err))
 		return 1
 	}
 
-	err := removeVersion(c.TerraformVersion, c.InstallPath, c.BinPath, c.Extension, args[0])
+	versions, err := c.selectVersions(args, all, allExceptActive)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Could not remove version: %s", err))
 		return 1
 	}
-	c.Ui.Output(fmt.Sprintf("Terraform v%s was successfully removed.", args[0]))
+	if len(versions) == 0 {
+		if !c.outputJSON(removeJSON{Removed: []removedJSON{}}) {
+			c.Ui.Output("No versions to remove.")
+		}
+		return 0
+	}
+	if containsString(versions, c.TerraformVersion) && !force {
+		err := fmt.Errorf("Terraform v%s is in use, pass --force to remove it anyway", c.TerraformVersion)
+		c.Ui.Error(fmt.Sprintf("Could not remove version: %s", err))
+		return 1
+	}
+
+	planned := make([]removedJSON, len(versions))
+	for i, v := range versions {
+		planned[i].Version = v
+		planned[i].Path, planned[i].Linked = helper.LinkedVersion(c.InstallPath, c.Extension, v)
+	}
+
+	if dryRun {
+		if !c.outputJSON(removeJSON{Removed: planned}) {
+			c.outputPlan(planned)
+		}
+		return 0
+	}
+
+	if !yes {
+		c.outputPlan(planned)
+		if !c.confirmRemoval(len(planned)) {
+			return 1
+		}
+	}
+	return c.removeVersions(planned)
+}
+
+// confirmRemoval asks for confirmation to remove count versions and reports whether it was given.
+func (m *Meta) confirmRemoval(count int) bool {
+	answer, err := m.Ui.Ask(fmt.Sprintf("Remove %d version(s)? [y/N]", count))
+	if err != nil {
+		m.Ui.Error(fmt.Sprintf("Could not confirm removal: %s, pass --yes to remove without confirmation", err))
+		return false
+	}
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "yes" && answer != "y" {
+		m.Ui.Error("Removal cancelled.")
+		return false
+	}
+	return true
+}
+
+// removeVersions removes the planned versions, reports each one and returns the exit status.
+// A version that cannot be removed does not stop the others from being removed.
+func (m *Meta) removeVersions(planned []removedJSON) int {
+	var failed int
+	result := removeJSON{Removed: []removedJSON{}}
+	for _, r := range planned {
+		err := removeVersion(m.TerraformVersion, m.InstallPath, m.BinPath, m.Extension, r.Version)
+		if err != nil {
+			failed++
+			r.Error = err.Error()
+		}
+		result.Removed = append(result.Removed, r)
+	}
+	if m.outputJSON(result) {
+		if failed > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	for _, r := range result.Removed {
+		switch {
+		case r.Error != "":
+			m.Ui.Error(fmt.Sprintf("Could not remove version %s: %s", r.Version, r.Error))
+		case r.Linked:
+			m.Ui.Output(fmt.Sprintf("Terraform %s was unregistered, %s was left in place.", r.Version, r.Path))
+		default:
+			m.Ui.Output(fmt.Sprintf("Terraform v%s was successfully removed.", r.Version))
+		}
+	}
+
+	if failed > 0 {
+		m.Ui.Error(fmt.Sprintf("Could not remove %d of %d versions.", failed, len(result.Removed)))
+		return 1
+	}
 	return 0
 }
 
+// selectVersions returns the installed versions matching the versions and constraints in specs, oldest first.
+// Every installed version is selected with all, and every one but the active version with allExceptActive.
+func (c *RemoveCommand) selectVersions(specs []string, all bool, allExceptActive bool) ([]string, error) {
+	installed, err := helper.GetInstalledVersions(c.InstallPath, c.Extension)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]bool{}
+	for _, v := range installed {
+		if all || (allExceptActive && v != c.TerraformVersion) {
+			selected[v] = true
+		}
+	}
+
+	for _, spec := range specs {
+		if !helper.IsConstraint(spec) {
+			if !containsString(installed, spec) {
+				return nil, fmt.Errorf("Terraform %s is not installed, run `tfvm list` for a list of installed versions", spec)
+			}
+			selected[spec] = true
+			continue
+		}
+
+		constraint, err := helper.ParseConstraint(spec)
+		if err != nil {
+			return nil, err
+		}
+		matched := false
+		for _, v := range installed {
+			if constraint.Check(v) {
+				selected[v] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no installed version meets %s", spec)
+		}
+	}
+
+	if allExceptActive {
+		delete(selected, c.TerraformVersion)
+	}
+
+	// Keep the order of the installed versions, which are sorted oldest first.
+	var versions []string
+	for _, v := range installed {
+		if selected[v] {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// outputPlan shows the versions that are about to be removed.
+func (c *RemoveCommand) outputPlan(planned []removedJSON) {
+	c.Ui.Output("The following versions will be removed:")
+	for _, r := range planned {
+		line := "  " + r.Version
+		switch {
+		case r.Version == c.TerraformVersion:
+			line += " (in use)"
+		case r.Linked:
+			line += fmt.Sprintf(" (linked, %s is kept)", r.Path)
+		}
+		c.Ui.Output(line)
+	}
+}
+
+// removeJSON is the result of tfvm remove with JSON output.
+// With --dry-run it lists the versions that would be removed.
+type removeJSON struct {
+	Removed []removedJSON `json:"removed"`
+}
+
+// removedJSON is a removed version in JSON output. Linked versions keep the binary at Path.
+// Error is set for a version that could not be removed.
+type removedJSON struct {
+	Version string `json:"version"`
+	Linked  bool   `json:"linked"`
+	Path    string `json:"path,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
 func (c *RemoveCommand) Synopsis() string {
-	return "Remove a specific version of Terraform"
+	return "Remove versions of Terraform"
 }
 
 func (c *RemoveCommand) Help() string {
 	helpText := `
-Usage: tfvm remove <version>
+Usage: tfvm remove [options] <version|constraint>...
 
-	Removes a specific Terraform version from the system.
+	Removes Terraform versions from the system.
+	Versions can be given by name or selected with a version constraint such as '< 1.0'.
+	Binaries registered with tfvm link are unregistered without deleting the original file.
+	The versions to be removed are shown and must be confirmed unless --yes is given.
 
 	For a list of installed versions, run:
 		tfvm list
+
+	Options:
+		--all	Remove every installed version
+		--all-except-active	Remove every installed version except the one in use
+		--yes, -y	Do not ask for confirmation
+		--force	Allow removing the version in use
+		--dry-run	Only show the versions that would be removed
+
+	Examples:
+		tfvm remove 1.0.0 1.1.0	Removes Terraform v1.0.0 and v1.1.0
+		tfvm remove '< 1.0'	Removes every version before v1.0.0
+		tfvm remove --all-except-active --yes	Removes every version except the one in use without asking
 	`
 
 	return strings.TrimSpace(helpText)
 }
 
+// removeVersion removes the specified version of Terraform from the install path.
 func removeVersion(
 	currentVersion string,
 	installPath string,
@@ -55,22 +256,29 @@ func removeVersion(
 	extension string,
 	version string,
 ) error {
+	lock, err := helper.LockExclusive(installPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	// Check if version is installed.
-	err := helper.IsInstalledVersion(installPath, extension, version)
+	err = helper.IsInstalledVersion(installPath, extension, version)
 	if err != nil {
 		return err
 	}
 
-	// Remove the version from the install path.
-	err = os.Remove(installPath + string(filepath.Separator) + "terraform" + version + extension)
+	// Remove the version directory from the install path.
+	err = os.RemoveAll(helper.VersionDir(installPath, version))
 	if err != nil {
 		return err
 	}
 
 	// Remove the binary from the binary path if it is the current version.
+	// It may already be gone, which leaves nothing to do.
 	if version == currentVersion {
-		err := os.Remove(binPath + string(filepath.Separator) + "terraform")
-		if err != nil {
+		err := os.Remove(binPath + string(filepath.Separator) + "terraform" + extension)
+		if err != nil && !os.IsNotExist(err) {
 			return err
 		}
 	}
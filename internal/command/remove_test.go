@@ -1,11 +1,15 @@
 package command
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/ehassett/tfvm/internal/helper"
 	"github.com/mitchellh/cli"
 )
 
@@ -33,16 +37,12 @@ func TestRemove(t *testing.T) {
 		t.Fatalf("cannot create stub binary: %s", err)
 	}
 
-	currentVerFileName := installDir + string(filepath.Separator) + "terraform1.0.0"
-	currentVerFile, err := os.Create(currentVerFileName)
-	if err != nil {
-		t.Fatalf("cannot create stub version file: %s", err)
-	}
+	currentVerFileName := stubVersion(t, installDir, "1.0.0")
 
-	verFileName := installDir + string(filepath.Separator) + "terraform0.15.0"
-	verFile, err := os.Create(verFileName)
-	if err != nil {
-		t.Fatalf("cannot create stub version file: %s", err)
+	verFileName := stubVersion(t, installDir, "0.15.0")
+
+	for _, v := range []string{"0.14.0", "0.13.0", "0.12.0", "0.11.0"} {
+		stubVersion(t, installDir, v)
 	}
 
 	removeTestCase := func(test func(t *testing.T, c *RemoveCommand, ui *cli.MockUi)) func(t *testing.T) {
@@ -54,7 +54,6 @@ func TestRemove(t *testing.T) {
 					TerraformVersion: "1.0.0",
 					InstallPath:      installDir,
 					BinPath:          binDir,
-					TempPath:         "tfvm.zip",
 					Extension:        "",
 					Ui:               ui,
 				},
@@ -64,9 +63,38 @@ func TestRemove(t *testing.T) {
 		}
 	}
 
+	// Pass in the current installed version without --force and expect an error with no file changes.
+	t.Run("current terraform version without force", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--yes", "1.0.0"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if _, err := os.Stat(currentVerFileName); os.IsNotExist(err) {
+			t.Fatalf("unexpectedly removed the current version file\nstderr: %s", ui.ErrorWriter.String())
+		}
+	}))
+
+	// Decline the confirmation and expect no file changes.
+	t.Run("cancelled removal", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		ui.InputReader = strings.NewReader("n\n")
+		status := c.Run([]string{"0.15.0"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.OutputWriter.String(), "The following versions will be removed:\n  0.15.0\n") {
+			t.Fatalf("expected a preview of the removal\nstdout: %s", ui.OutputWriter.String())
+		}
+
+		if _, err := os.Stat(verFileName); os.IsNotExist(err) {
+			t.Fatalf("unexpectedly removed the version file\nstderr: %s", ui.ErrorWriter.String())
+		}
+	}))
+
 	// Pass in the current installed version and expect removal of both the bin/ and versions/ files.
 	t.Run("current terraform version", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
-		status := c.Run([]string{"1.0.0"})
+		status := c.Run([]string{"--yes", "--force", "1.0.0"})
 		if status != 0 {
 			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
 		}
@@ -75,23 +103,113 @@ func TestRemove(t *testing.T) {
 			t.Fatalf("failed to remove current executable\nstderr: %s", ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(currentVerFile.Name()); !os.IsNotExist(err) {
+		if _, err := os.Stat(currentVerFileName); !os.IsNotExist(err) {
 			t.Fatalf("failed to remove current version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 	}))
 
 	// Pass in an installed version and expect removal of the versions/ file.
 	t.Run("installed terraform version", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		ui.InputReader = strings.NewReader("y\n")
 		status := c.Run([]string{"0.15.0"})
 		if status != 0 {
 			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(verFile.Name()); !os.IsNotExist(err) {
+		if _, err := os.Stat(verFileName); !os.IsNotExist(err) {
 			t.Fatalf("failed to remove current version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 	}))
 
+	// Preview the removal of a constraint and expect no file changes.
+	t.Run("dry run", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--dry-run", "< 0.14"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if ui.OutputWriter.String() != "The following versions will be removed:\n  0.11.0\n  0.12.0\n  0.13.0\n" {
+			t.Fatalf("unexpected preview\nstdout: %s", ui.OutputWriter.String())
+		}
+
+		if versions, _ := helper.GetInstalledVersions(installDir, ""); len(versions) != 4 {
+			t.Fatalf("unexpectedly removed versions, %v remain", versions)
+		}
+	}))
+
+	// Pass in several versions and a constraint and expect each match to be removed.
+	t.Run("several versions", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--yes", "0.11.0", "< 0.13"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		versions, _ := helper.GetInstalledVersions(installDir, "")
+		if !reflect.DeepEqual(versions, []string{"0.13.0", "0.14.0"}) {
+			t.Fatalf("expected 0.11.0 and 0.12.0 to be removed, %v remain", versions)
+		}
+	}))
+
+	// Remove everything but the active version and expect it to remain.
+	t.Run("all except active", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		c.TerraformVersion = "0.14.0"
+		status := c.Run([]string{"--yes", "--all-except-active"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		versions, _ := helper.GetInstalledVersions(installDir, "")
+		if !reflect.DeepEqual(versions, []string{"0.14.0"}) {
+			t.Fatalf("expected only the active version to remain, %v remain", versions)
+		}
+	}))
+
+	// Remove the active version after its executable is gone and expect it to be removed without an error.
+	t.Run("active version without executable", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		stubVersion(t, installDir, "0.13.0")
+		c.TerraformVersion = "0.13.0"
+
+		status := c.Run([]string{"--yes", "--force", "0.13.0"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		versions, _ := helper.GetInstalledVersions(installDir, "")
+		if !reflect.DeepEqual(versions, []string{"0.14.0"}) {
+			t.Fatalf("expected 0.13.0 to be removed, %v remain", versions)
+		}
+	}))
+
+	// Remove a version that fails and expect the others to be removed and every result to be reported.
+	t.Run("partial failure", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		// 0.13.0 was planned for removal but is no longer installed, as if another process removed it first.
+		c.Ui = NewJSONUi(ui)
+		status := c.removeVersions([]removedJSON{{Version: "0.13.0"}, {Version: "0.14.0"}})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstdout: %s", status, ui.OutputWriter.String())
+		}
+
+		var result removeJSON
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &result); err != nil {
+			t.Fatalf("cannot decode output: %s\nstdout: %s", err, ui.OutputWriter.String())
+		}
+		if len(result.Removed) != 2 || result.Removed[0].Error == "" || result.Removed[1].Error != "" {
+			t.Fatalf("expected only 0.13.0 to fail, got %+v", result.Removed)
+		}
+
+		if versions, _ := helper.GetInstalledVersions(installDir, ""); len(versions) != 0 {
+			t.Fatalf("expected 0.14.0 to be removed, %v remain", versions)
+		}
+	}))
+
+	// Pass in a constraint no version meets and expect an error.
+	t.Run("unmet constraint", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--yes", "> 2.0"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+	}))
+
 	// Pass in a not installed version and expect an error.
 	t.Run("not installed terraform version", removeTestCase(func(t *testing.T, c *RemoveCommand, ui *cli.MockUi) {
 		status := c.Run([]string{"0.13.5"})
@@ -0,0 +1,145 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ehassett/tfvm/internal/helper"
+)
+
+// retainedVersion is an installed version considered for eviction.
+type retainedVersion struct {
+	version   string
+	lastUsed  time.Time
+	size      int64
+	protected bool
+}
+
+// retentionLimits returns the maximum number of versions and bytes to keep installed,
+// set with max_versions and max_size. Zero means no limit.
+func retentionLimits() (int, int64, error) {
+	var maxVersions int
+	var maxBytes int64
+	var err error
+
+	if v := helper.ConfigValue("max_versions"); v != "" {
+		maxVersions, err = strconv.Atoi(v)
+		if err != nil || maxVersions < 0 {
+			return 0, 0, fmt.Errorf("invalid max_versions %q", v)
+		}
+	}
+	if v := helper.ConfigValue("max_size"); v != "" {
+		maxBytes, err = helper.ParseSize(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid max_size: %w", err)
+		}
+	}
+	return maxVersions, maxBytes, nil
+}
+
+// enforceLimits removes the least recently used versions until the store is within the retention limits,
+// and returns the removed versions. The active version, versions pinned by .tfversion files,
+// linked binaries and the versions in keep, such as those just installed, are never removed.
+// Failures are only warned about, since the install that triggered them has succeeded.
+func (m *Meta) enforceLimits(keep ...string) []string {
+	maxVersions, maxBytes, err := retentionLimits()
+	if err == nil && maxVersions == 0 && maxBytes == 0 {
+		return nil
+	}
+
+	var evicted []string
+	if err == nil {
+		evicted, err = m.evictVersions(maxVersions, maxBytes, keep)
+	}
+	if err != nil {
+		m.Ui.Warn(fmt.Sprintf("Could not remove versions to stay within the configured limits: %s", err))
+	}
+	return evicted
+}
+
+// outputEvicted reports the versions removed by enforceLimits.
+func (m *Meta) outputEvicted(evicted []string) {
+	for _, v := range evicted {
+		m.Ui.Output(fmt.Sprintf("Terraform v%s was removed as the least recently used version, to stay within the configured limits.", v))
+	}
+}
+
+// evictVersions removes versions as described by enforceLimits.
+func (m *Meta) evictVersions(maxVersions int, maxBytes int64, keep []string) ([]string, error) {
+	installed, err := helper.GetInstalledVersions(m.InstallPath, m.Extension)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced, err := m.referencedVersions(filepath.SplitList(helper.ConfigValue("project_roots")))
+	if err != nil {
+		return nil, err
+	}
+	protected := stringSet(append(matchingVersions(referenced, installed), keep...))
+	protected[m.TerraformVersion] = true
+
+	var versions []retainedVersion
+	for _, v := range installed {
+		manifest, err := helper.ReadManifest(m.InstallPath, v)
+		if err != nil {
+			return nil, err
+		}
+		// Linked binaries take no space in the store and are not counted.
+		if manifest.Source == helper.SourceLinked {
+			continue
+		}
+
+		r := retainedVersion{version: v, lastUsed: manifest.InstalledAt, protected: protected[v]}
+		if manifest.LastUsed != nil {
+			r.lastUsed = *manifest.LastUsed
+		}
+		r.size, err = helper.VersionSize(m.InstallPath, m.Extension, v)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, r)
+	}
+
+	var evicted []string
+	for _, v := range selectEvictions(versions, maxVersions, maxBytes) {
+		if err := removeVersion(m.TerraformVersion, m.InstallPath, m.BinPath, m.Extension, v); err != nil {
+			return evicted, err
+		}
+		evicted = append(evicted, v)
+	}
+	return evicted, nil
+}
+
+// selectEvictions returns the unprotected versions to remove, least recently used first, so that at most
+// maxVersions versions using at most maxBytes remain. A zero limit is not enforced. Protected versions count
+// towards the limits, so the limits may still be exceeded when only protected versions remain.
+func selectEvictions(versions []retainedVersion, maxVersions int, maxBytes int64) []string {
+	count := len(versions)
+	var total int64
+	for _, v := range versions {
+		total += v.size
+	}
+
+	candidates := make([]retainedVersion, len(versions))
+	copy(candidates, versions)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].lastUsed.Before(candidates[j].lastUsed)
+	})
+
+	var evicted []string
+	for _, v := range candidates {
+		if (maxVersions == 0 || count <= maxVersions) && (maxBytes == 0 || total <= maxBytes) {
+			break
+		}
+		if v.protected {
+			continue
+		}
+		evicted = append(evicted, v.version)
+		count--
+		total -= v.size
+	}
+	return evicted
+}
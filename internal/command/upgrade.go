@@ -0,0 +1,235 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ehassett/tfvm/internal/helper"
+)
+
+// UpgradeCommand is a Command that installs the newer releases reported by tfvm outdated.
+type UpgradeCommand struct {
+	Meta
+}
+
+// upgradeJSON is the result of tfvm upgrade with JSON output.
+type upgradeJSON struct {
+	Upgrades []upgradeResultJSON `json:"upgrades"`
+	Used     string              `json:"used,omitempty"`
+	Removed  []string            `json:"removed"`
+	Evicted  []string            `json:"evicted,omitempty"`
+}
+
+// upgradeResultJSON is the outcome of upgrading one version in JSON output.
+// Installed is false for releases that were already installed or failed with Error.
+type upgradeResultJSON struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Installed bool   `json:"installed"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (c *UpgradeCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
+	var patch, minor, use, remove, quiet bool
+	var parallelism int
+
+	cmdFlags := c.flagSet("upgrade")
+	cmdFlags.BoolVar(&patch, "patch", false, "patch")
+	cmdFlags.BoolVar(&minor, "minor", false, "minor")
+	cmdFlags.BoolVar(&use, "use", false, "use")
+	cmdFlags.BoolVar(&remove, "remove", false, "remove")
+	cmdFlags.BoolVar(&quiet, "quiet", false, "quiet")
+	cmdFlags.BoolVar(&quiet, "q", false, "quiet")
+	cmdFlags.BoolVar(&helper.Refresh, "refresh", false, "refresh")
+	cmdFlags.IntVar(&parallelism, "parallelism", 4, "parallelism")
+	if err := cmdFlags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse arguments: %s", err))
+		return 1
+	}
+	if patch && minor {
+		c.Ui.Error("Could not upgrade versions: --patch and --minor cannot be combined")
+		return 1
+	}
+
+	versions, err := findOutdated(c.TerraformVersion, c.InstallPath, c.Extension)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Could not check for newer versions: %s", err))
+		return 1
+	}
+
+	// Pick the release each version is upgraded to, which is a patch release unless --minor is given.
+	var upgrades []outdatedVersion
+	var targets []string
+	targetOf := map[string]string{}
+	for _, v := range versions {
+		target := v.Patch
+		if minor && v.Minor != "" {
+			target = v.Minor
+		}
+		if target == "" || !v.Installed {
+			continue
+		}
+		upgrades = append(upgrades, v)
+		targetOf[v.Version] = target
+		if !containsString(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+
+	result := upgradeJSON{Upgrades: []upgradeResultJSON{}, Removed: []string{}}
+	if len(upgrades) == 0 {
+		if !c.outputJSON(result) {
+			c.Ui.Output("All installed versions are up to date.")
+		}
+		return 0
+	}
+
+	progress := newProgress(lineUi{c.Ui}, quiet)
+	installed := map[string]installResult{}
+	for _, r := range installVersions(c.TerraformVersion, c.InstallPath, c.BinPath, c.Extension, targets, parallelism, progress) {
+		installed[r.spec] = r
+	}
+
+	var failed int
+	current := c.TerraformVersion
+	for _, v := range upgrades {
+		r := installed[targetOf[v.Version]]
+		upgrade := upgradeResultJSON{From: v.Version, To: r.version, Installed: r.installed}
+		if r.err != nil {
+			failed++
+			upgrade.Error = r.err.Error()
+			result.Upgrades = append(result.Upgrades, upgrade)
+			continue
+		}
+		result.Upgrades = append(result.Upgrades, upgrade)
+
+		if use && v.Active {
+			if err := useVersion(current, c.InstallPath, c.BinPath, c.Extension, r.version); err != nil {
+				c.Ui.Error(fmt.Sprintf("Failed to change versions: %s", err))
+				return 1
+			}
+			current = r.version
+			result.Used = r.version
+		}
+	}
+
+	// Removing superseded versions may already bring the store within the retention limits.
+	c.TerraformVersion = current
+	if remove {
+		removed, err := c.removeSuperseded(result.Upgrades, current)
+		result.Removed = append(result.Removed, removed...)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Could not remove superseded versions: %s", err))
+			return 1
+		}
+	}
+
+	result.Evicted = c.enforceLimits(targets...)
+
+	if c.outputJSON(result) {
+		if failed > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	for _, u := range result.Upgrades {
+		switch {
+		case u.Error != "":
+			c.Ui.Error(fmt.Sprintf("  %s -> %s: failed: %s", u.From, u.To, u.Error))
+		case u.Installed:
+			c.Ui.Output(fmt.Sprintf("  %s -> %s: installed", u.From, u.To))
+		default:
+			c.Ui.Output(fmt.Sprintf("  %s -> %s: already installed", u.From, u.To))
+		}
+	}
+	if result.Used != "" {
+		c.Ui.Output(fmt.Sprintf("Now using Terraform v%s", result.Used))
+	}
+	for _, v := range result.Removed {
+		c.Ui.Output(fmt.Sprintf("Terraform v%s was successfully removed.", v))
+	}
+	c.outputEvicted(result.Evicted)
+
+	if failed > 0 {
+		c.Ui.Error(fmt.Sprintf("Could not upgrade %d of %d versions.", failed, len(result.Upgrades)))
+		return 1
+	}
+	return 0
+}
+
+// removeSuperseded removes the installed versions in the minor lines of successful upgrades that are
+// older than the release they were upgraded to. The current version and the versions matching .tfversion are kept.
+func (c *UpgradeCommand) removeSuperseded(upgrades []upgradeResultJSON, current string) ([]string, error) {
+	installed, err := helper.GetInstalledVersions(c.InstallPath, c.Extension)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := getProjectVersion()
+	if err != nil {
+		return nil, err
+	}
+	var pinnedSet map[string]bool
+	if pinned != "" {
+		pinnedSet = stringSet(matchingVersions([]string{pinned}, installed))
+	}
+
+	var removed []string
+	for _, u := range upgrades {
+		if u.Error != "" {
+			continue
+		}
+
+		line, _ := helper.MinorLine(u.From)
+		for _, v := range installed {
+			if v == current || pinnedSet[v] || containsString(removed, v) {
+				continue
+			}
+			if _, linked := helper.LinkedVersion(c.InstallPath, c.Extension, v); linked {
+				continue
+			}
+			if l, err := helper.MinorLine(v); err != nil || l != line || helper.CompareVersions(v, u.To) >= 0 {
+				continue
+			}
+
+			if err := removeVersion(current, c.InstallPath, c.BinPath, c.Extension, v); err != nil {
+				return removed, err
+			}
+			removed = append(removed, v)
+		}
+	}
+	return removed, nil
+}
+
+func (c *UpgradeCommand) Synopsis() string {
+	return "Install newer releases of installed versions of Terraform"
+}
+
+func (c *UpgradeCommand) Help() string {
+	helpText := `
+Usage: tfvm upgrade [options]
+
+	Installs the newer releases reported by tfvm outdated.
+	By default each minor line is upgraded to its newest patch release.
+	With --minor, versions are upgraded to the newest release of their major version instead.
+
+	Options:
+		--patch	Upgrade to the newest patch release of each minor line (default)
+		--minor	Upgrade to the newest minor release of each major version
+		--use	Switch to the upgrade of the active version
+		--remove	Remove the versions that were upgraded from, except the active and pinned versions
+		--quiet, -q	Do not report download progress
+		--refresh	Fetch the list of available versions again instead of using the cached copy
+		--parallelism	Number of versions to download at once (default 4)
+
+	Examples:
+		tfvm upgrade	Installs the newest patch release of every installed minor line
+		tfvm upgrade --use --remove	Also switches to the new patch release and removes the old ones
+	`
+
+	return strings.TrimSpace(helpText)
+}
@@ -0,0 +1,105 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ehassett/tfvm/internal/helper"
+	"github.com/mitchellh/cli"
+)
+
+// TestUpgrade serves releases from a local mirror and tests various UpgradeCommand cases.
+func TestUpgrade(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-command-upgrade")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	installDir, err := ioutil.TempDir(workDir, "versions")
+	if err != nil {
+		t.Fatalf("cannot create versions directory: %s", err)
+	}
+
+	binDir, err := ioutil.TempDir(workDir, "bin")
+	if err != nil {
+		t.Fatalf("cannot create bin directory: %s", err)
+	}
+
+	arch, err := getArchitecture()
+	if err != nil {
+		t.Skipf("unsupported platform: %s", err)
+	}
+
+	stubVersion(t, installDir, "1.5.5")
+	stubVersion(t, installDir, "1.5.6")
+
+	server := newMirror(t, arch, "1.5.7", "1.5.6", "1.5.5")
+	defer server.Close()
+	os.Setenv("TFVM_MIRROR", server.URL)
+	defer os.Unsetenv("TFVM_MIRROR")
+
+	upgradeTestCase := func(test func(t *testing.T, c *UpgradeCommand, ui *cli.MockUi)) func(t *testing.T) {
+		return func(t *testing.T) {
+			ui := new(cli.MockUi)
+
+			c := &UpgradeCommand{
+				Meta: Meta{
+					TerraformVersion: "1.5.5",
+					InstallPath:      installDir,
+					BinPath:          binDir,
+					Extension:        "",
+					Ui:               ui,
+				},
+			}
+
+			test(t, c, ui)
+		}
+	}
+
+	// Combine --patch and --minor and expect an error.
+	t.Run("conflicting flags", upgradeTestCase(func(t *testing.T, c *UpgradeCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--patch", "--minor"})
+		if status != 1 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+	}))
+
+	// Upgrade, switch and remove, and expect only the new patch release to remain in use.
+	t.Run("upgrade patch releases", upgradeTestCase(func(t *testing.T, c *UpgradeCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--quiet", "--use", "--remove"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if !strings.Contains(ui.OutputWriter.String(), "1.5.5 -> 1.5.7: installed") || !strings.Contains(ui.OutputWriter.String(), "Now using Terraform v1.5.7") {
+			t.Fatalf("expected the active version to be upgraded and used\nstdout: %s", ui.OutputWriter.String())
+		}
+
+		versions, _ := helper.GetInstalledVersions(installDir, "")
+		if !reflect.DeepEqual(versions, []string{"1.5.7"}) {
+			t.Fatalf("expected only the upgrade to remain, found %v", versions)
+		}
+
+		if _, err := os.Stat(binDir + string(filepath.Separator) + "terraform"); os.IsNotExist(err) {
+			t.Fatalf("failed to use the upgraded version\nstderr: %s", ui.ErrorWriter.String())
+		}
+	}))
+
+	// Upgrade again and expect nothing to do.
+	t.Run("up to date", upgradeTestCase(func(t *testing.T, c *UpgradeCommand, ui *cli.MockUi) {
+		c.TerraformVersion = "1.5.7"
+		status := c.Run([]string{"--quiet"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if ui.OutputWriter.String() != "All installed versions are up to date.\n" {
+			t.Fatalf("unexpected output\nstdout: %s", ui.OutputWriter.String())
+		}
+	}))
+}
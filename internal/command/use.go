@@ -17,31 +17,55 @@ type UseCommand struct {
 }
 
 func (c *UseCommand) Run(args []string) int {
+	if !c.initialize() {
+		return 1
+	}
+
 	var version string
+	var autoInstall, quiet bool
+
+	cmdFlags := c.flagSet("use")
+	cmdFlags.BoolVar(&autoInstall, "install", false, "install")
+	cmdFlags.BoolVar(&quiet, "quiet", false, "quiet")
+	cmdFlags.BoolVar(&quiet, "q", false, "quiet")
+	cmdFlags.BoolVar(&helper.Refresh, "refresh", false, "refresh")
+	if err := cmdFlags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse arguments: %s", err))
+		return 1
+	}
+	args = cmdFlags.Args()
 
 	if len(args) < 1 {
-		// Get working directory.
-		cwd, err := os.Getwd()
+		// Read .tfversion from the working directory, falling back to the configured default.
+		pinned, err := getProjectVersion()
 		if err != nil {
-			c.Ui.Error(fmt.Sprintf("Failed to get working directory: %s", err))
+			c.Ui.Error(fmt.Sprintf("Failed to read .tfversion: %s", err))
 			return 1
 		}
-
-		// Test if .tfversion exists in working directory.
-		if _, err := os.Stat(cwd + string(filepath.Separator) + ".tfversion"); os.IsNotExist(err) {
-			err := errors.New("no version specified in command or .tfversion")
+		if pinned == "" {
+			pinned = helper.ConfigValue("default_version")
+		}
+		if pinned == "" {
+			err := errors.New("no version specified in command, .tfversion or default_version")
 			c.Ui.Error(fmt.Sprintf("Failed to change versions: %s", err))
 			return 1
 		}
+		version = pinned
+	} else {
+		version = args[0]
+	}
+
+	result := useJSON{Previous: c.TerraformVersion}
 
-		// Read .tfversion.
-		version, err = getDirVersion()
+	// Install the version first if it is missing and auto-install is enabled.
+	if autoInstall || helper.ConfigBool("auto_install") {
+		resolved, installed, err := ensureInstalled(c.TerraformVersion, c.InstallPath, c.BinPath, c.Extension, version, newProgress(c.Ui, quiet))
 		if err != nil {
-			c.Ui.Error(fmt.Sprintf("Failed to read .tfversion: %s", err))
+			c.Ui.Error(fmt.Sprintf("Could not install specified version: %s", err))
 			return 1
 		}
-	} else {
-		version = args[0]
+		version = resolved
+		result.Installed = installed
 	}
 
 	err := useVersion(c.TerraformVersion, c.InstallPath, c.BinPath, c.Extension, version)
@@ -49,70 +73,146 @@ func (c *UseCommand) Run(args []string) int {
 		c.Ui.Error(fmt.Sprintf("Failed to change versions: %s", err))
 		return 1
 	}
-	c.Ui.Output(fmt.Sprintf("Now using Terraform v%s", version))
+	result.Version = version
+
+	// The version just installed is now the active one, so the previous version may be evicted.
+	if result.Installed {
+		c.TerraformVersion = version
+		result.Evicted = c.enforceLimits(version)
+	}
+
+	if c.outputJSON(result) {
+		return 0
+	}
+	if result.Installed {
+		c.Ui.Output(fmt.Sprintf("Terraform v%s successfully installed.", result.Version))
+	}
+	c.Ui.Output(fmt.Sprintf("Now using Terraform v%s", result.Version))
+	c.outputEvicted(result.Evicted)
 	return 0
 }
 
+// useJSON is the result of tfvm use with JSON output.
+type useJSON struct {
+	Version   string   `json:"version"`
+	Previous  string   `json:"previous"`
+	Installed bool     `json:"installed"`
+	Evicted   []string `json:"evicted,omitempty"`
+}
+
 func (c *UseCommand) Synopsis() string {
 	return "Select a version of Terraform to use"
 }
 
 func (c *UseCommand) Help() string {
 	helpText := `
-Usage: tfvm use [version]
+Usage: tfvm use [options] [version]
 
 	Selects a Terraform version to use.
-	If no version is specified, tfvm will try to select the version specified in .tfversion if it exists in the current directory.
+	If no version is specified, tfvm will try to select the version specified in .tfversion if it exists in the current directory,
+	and will otherwise select the default_version setting.
 
 	For a list of installed versions, run:
 		tfvm list
+
+	Options:
+		--install	Install the version first if it is not installed yet.
+				This can also be enabled with the auto_install setting or TFVM_AUTO_INSTALL=1.
+		--quiet, -q	Do not report download progress
+		--refresh	Fetch the list of available versions again instead of using the cached copy
 	`
 
 	return strings.TrimSpace(helpText)
 }
 
-// useVersion copies the appropriate binary version to the binPath to be used.
+// useVersion atomically links the appropriate binary version into the binPath to be used.
 func useVersion(
 	currentVersion string,
 	installPath string,
 	binPath string,
 	extension string,
 	version string) error {
+	lock, err := helper.LockExclusive(installPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	// Check if specified version is installed.
-	err := helper.IsInstalledVersion(installPath, extension, version)
+	err = helper.IsInstalledVersion(installPath, extension, version)
 	if err != nil {
 		return err
 	}
 
+	// Usage is only informational, so failing to record it does not stop the switch.
+	// The version switched away from was in use until now, so it is recorded too.
+	helper.RecordUse(installPath, version)
+	if currentVersion != "" && currentVersion != version {
+		helper.RecordUse(installPath, currentVersion)
+	}
+
 	// Return if desired version is already current.
 	if version == currentVersion {
 		return nil
 	}
 
-	// Remove binary from binary path if it exists.
-	_, err = os.Stat(binPath + string(filepath.Separator) + "terraform" + extension)
-	if !os.IsNotExist(err) {
-		err = os.Remove(binPath + string(filepath.Separator) + "terraform" + extension)
-		if err != nil {
-			return err
-		}
+	if err := os.MkdirAll(binPath, 0755); err != nil {
+		return err
+	}
+
+	// Link the new binary under a temporary name first so the current binary stays in place on failure.
+	binFile := binPath + string(filepath.Separator) + "terraform" + extension
+	tmpFile := fmt.Sprintf("%s.%d.tmp", binFile, os.Getpid())
+	os.Remove(tmpFile)
+
+	// Linked binaries are symlinked directly, since they may live on another filesystem.
+	if target, ok := helper.LinkedVersion(installPath, extension, version); ok {
+		err = os.Symlink(target, tmpFile)
+	} else if helper.ConfigValue("link_mode") == helper.LinkSymbolic {
+		err = os.Symlink(helper.BinaryPath(installPath, extension, version), tmpFile)
+	} else {
+		err = os.Link(helper.BinaryPath(installPath, extension, version), tmpFile)
+	}
+	if err != nil {
+		return err
 	}
 
-	// Link new binary to binary path.
-	err = os.Link(installPath+string(filepath.Separator)+"terraform"+version+extension, binPath+string(filepath.Separator)+"terraform"+extension)
+	// Replace the current binary in a single step.
+	err = os.Rename(tmpFile, binFile)
 	if err != nil {
+		os.Remove(tmpFile)
 		return err
 	}
 
 	return nil
 }
 
+// getProjectVersion returns the version pinned by a .tfversion file in the working directory.
+// An empty version with no error means the directory does not pin a version.
+func getProjectVersion() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(cwd + string(filepath.Separator) + ".tfversion"); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	return getDirVersion()
+}
+
 // getDirVersion reads the version from a .tfversion file.
 func getDirVersion() (string, error) {
+	return readVersionFile(".tfversion")
+}
+
+// readVersionFile reads the version from the .tfversion file at path.
+func readVersionFile(path string) (string, error) {
 	var dirVersion string = ""
 
 	// Open file for reading.
-	f, err := os.OpenFile(".tfversion", os.O_RDWR, 0600)
+	f, err := os.Open(path)
 	if err != nil {
 		return dirVersion, err
 	}
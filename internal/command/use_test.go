@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mitchellh/cli"
@@ -34,17 +35,9 @@ func TestUse(t *testing.T) {
 		t.Fatalf("cannot create stub binary: %s", err)
 	}
 
-	currentVerFileName := installDir + string(filepath.Separator) + "terraform1.0.0"
-	currentVerFile, err := os.Create(currentVerFileName)
-	if err != nil {
-		t.Fatalf("cannot create stub version file: %s", err)
-	}
+	currentVerFileName := stubVersion(t, installDir, "1.0.0")
 
-	verFileName := installDir + string(filepath.Separator) + "terraform0.15.0"
-	verFile, err := os.Create(verFileName)
-	if err != nil {
-		t.Fatalf("cannot create stub version file: %s", err)
-	}
+	verFileName := stubVersion(t, installDir, "0.15.0")
 
 	useTestCase := func(test func(t *testing.T, c *UseCommand, ui *cli.MockUi)) func(t *testing.T) {
 		return func(t *testing.T) {
@@ -55,7 +48,6 @@ func TestUse(t *testing.T) {
 					TerraformVersion: "1.0.0",
 					InstallPath:      installDir,
 					BinPath:          binDir,
-					TempPath:         "tfvm.zip",
 					Extension:        "",
 					Ui:               ui,
 				},
@@ -76,13 +68,17 @@ func TestUse(t *testing.T) {
 			t.Fatalf("failed to keep an executable\nstderr: %s", ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(verFile.Name()); os.IsNotExist(err) {
+		if _, err := os.Stat(verFileName); os.IsNotExist(err) {
 			t.Fatalf("failed to keep the selected version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(currentVerFile.Name()); os.IsNotExist(err) {
+		if _, err := os.Stat(currentVerFileName); os.IsNotExist(err) {
 			t.Fatalf("failed to keep the previous version file\nstderr: %s", ui.ErrorWriter.String())
 		}
+
+		if files, _ := ioutil.ReadDir(binDir); len(files) != 1 {
+			t.Fatalf("expected only the executable in the bin directory, found %d files", len(files))
+		}
 	}))
 
 	// Pass in the current version and expect no errors or file changes.
@@ -96,15 +92,31 @@ func TestUse(t *testing.T) {
 			t.Fatalf("failed to keep an executable\nstderr: %s", ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(verFile.Name()); os.IsNotExist(err) {
+		if _, err := os.Stat(verFileName); os.IsNotExist(err) {
 			t.Fatalf("failed to keep the selected version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(currentVerFile.Name()); os.IsNotExist(err) {
+		if _, err := os.Stat(currentVerFileName); os.IsNotExist(err) {
 			t.Fatalf("failed to keep the previous version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 	}))
 
+	// Pass in an installed version with --install and expect it to be used without installing.
+	t.Run("auto-install installed terraform version", useTestCase(func(t *testing.T, c *UseCommand, ui *cli.MockUi) {
+		status := c.Run([]string{"--install", "0.15.0"})
+		if status != 0 {
+			t.Fatalf("unexpected error code %d\nstderr: %s", status, ui.ErrorWriter.String())
+		}
+
+		if strings.Contains(ui.OutputWriter.String(), "installed") {
+			t.Fatalf("unexpectedly installed an existing version\nstdout: %s", ui.OutputWriter.String())
+		}
+
+		if _, err := os.Stat(binFile.Name()); os.IsNotExist(err) {
+			t.Fatalf("failed to keep an executable\nstderr: %s", ui.ErrorWriter.String())
+		}
+	}))
+
 	// Pass in an invalid version and expect an error.
 	t.Run("invalid terraform version", useTestCase(func(t *testing.T, c *UseCommand, ui *cli.MockUi) {
 		status := c.Run([]string{"invalid_version"})
@@ -154,11 +166,11 @@ func TestUse(t *testing.T) {
 			t.Fatalf("unexpectedly removed the executable\nstderr: %s", ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(verFile.Name()); os.IsNotExist(err) {
+		if _, err := os.Stat(verFileName); os.IsNotExist(err) {
 			t.Fatalf("unexpectedly removed the selected version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 
-		if _, err := os.Stat(currentVerFile.Name()); os.IsNotExist(err) {
+		if _, err := os.Stat(currentVerFileName); os.IsNotExist(err) {
 			t.Fatalf("unexpectedly removed the previous version file\nstderr: %s", ui.ErrorWriter.String())
 		}
 	}))
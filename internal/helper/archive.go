@@ -0,0 +1,207 @@
+package helper
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Verification policies, set with TFVM_VERIFY.
+const (
+	// VerifyRequired fails installs whose checksums cannot be fetched. This is the default.
+	VerifyRequired = "required"
+
+	// VerifyOptional verifies archives when checksums are available.
+	VerifyOptional = "optional"
+
+	// VerifyOff never verifies archives.
+	VerifyOff = "off"
+)
+
+// VerifyPolicy returns how downloaded archives are verified.
+func VerifyPolicy() string {
+	switch policy := ConfigValue("verify"); policy {
+	case VerifyOptional, VerifyOff:
+		return policy
+	}
+	return VerifyRequired
+}
+
+// ArchivePath returns the directory verified release archives are cached in,
+// which is TFVM_CACHE_DIR if set and the archives directory under CachePath otherwise.
+// Archives are not cached when it is empty.
+func ArchivePath() string {
+	if dir := os.Getenv("TFVM_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	if CachePath == "" {
+		return ""
+	}
+	return CachePath + string(filepath.Separator) + "archives"
+}
+
+// ArchiveName returns the file name of the release archive of version for arch, such as linux_amd64.
+func ArchiveName(version string, arch string) string {
+	return "terraform_" + version + "_" + arch + ".zip"
+}
+
+// SumsName returns the file name of the checksums published for version.
+func SumsName(version string) string {
+	return "terraform_" + version + "_SHA256SUMS"
+}
+
+// VerifyArchive checks the archive at path against its entry, named name, in the checksums file at sumsPath.
+func VerifyArchive(path string, sumsPath string, name string) error {
+	expected, err := lookupSum(sumsPath, name)
+	if err != nil {
+		return err
+	}
+
+	actual, err := FileSum(path)
+	if err != nil {
+		return err
+	}
+
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+	return nil
+}
+
+// FileSum returns the hex encoded SHA256 checksum of the file at path.
+func FileSum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lookupSum finds the checksum of name in the checksums file at sumsPath.
+func lookupSum(sumsPath string, name string) (string, error) {
+	f, err := os.Open(sumsPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", name, filepath.Base(sumsPath))
+}
+
+// CachedArchive returns the path of the cached archive of version for arch if it is cached and still verifies.
+func CachedArchive(version string, arch string) (string, bool) {
+	dir := ArchivePath()
+	if dir == "" {
+		return "", false
+	}
+
+	name := ArchiveName(version, arch)
+	path := dir + string(filepath.Separator) + name
+	sumsPath := dir + string(filepath.Separator) + SumsName(version)
+	if err := VerifyArchive(path, sumsPath, name); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// CacheArchive copies a verified archive of version and its checksums file into the archive cache.
+func CacheArchive(path string, sumsPath string, version string) error {
+	dir := ArchivePath()
+	if dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := copyFile(sumsPath, dir+string(filepath.Separator)+SumsName(version)); err != nil {
+		return err
+	}
+	return copyFile(path, dir+string(filepath.Separator)+filepath.Base(path))
+}
+
+// copyFile atomically replaces dest with a copy of src.
+func copyFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dest), ".copy-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dest)
+}
+
+// CachedFile is a file in the archive cache.
+type CachedFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// GetCachedArchives returns the archives and checksum files in the archive cache.
+func GetCachedArchives() ([]CachedFile, error) {
+	var files []CachedFile
+
+	dir := ArchivePath()
+	if dir == "" {
+		return files, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return files, err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), "terraform_") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return files, err
+		}
+		files = append(files, CachedFile{Name: e.Name(), Size: info.Size()})
+	}
+	return files, nil
+}
+
+// RemoveCachedArchive removes the named file from the archive cache.
+func RemoveCachedArchive(name string) error {
+	return os.Remove(ArchivePath() + string(filepath.Separator) + filepath.Base(name))
+}
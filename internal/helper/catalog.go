@@ -0,0 +1,192 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultCatalogTTL is how long a cached catalog is used before it is revalidated.
+// It can be overridden with TFVM_CACHE_TTL.
+const defaultCatalogTTL = time.Hour
+
+var (
+	// CachePath is the directory the release catalog is cached in.
+	// The catalog is not cached on disk when it is empty.
+	CachePath string
+
+	// Refresh forces the catalog to be revalidated with the mirror even if the cached copy is fresh.
+	Refresh bool
+)
+
+// catalog is the in-memory copy of the release catalog, shared by every lookup in this invocation.
+var catalog *cachedCatalog
+
+// catalogMu guards catalog and Refresh against concurrent installs.
+var catalogMu sync.Mutex
+
+// cachedCatalog is the release catalog of a mirror as stored on disk.
+type cachedCatalog struct {
+	Mirror       string    `json:"mirror"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	Releases     []string  `json:"releases"`
+}
+
+// getReleases returns every release listed by the mirror, newest first, including pre-releases.
+// The catalog is read from the on-disk cache while it is fresh and revalidated with a conditional request otherwise.
+// When Offline is set, the cached catalog is used however old it is.
+func getReleases() ([]string, error) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	mirror := MirrorURL()
+	if catalog != nil && catalog.Mirror == mirror && !Refresh {
+		return catalog.Releases, nil
+	}
+
+	cached := readCatalog(mirror)
+	if cached != nil && !Refresh && time.Since(cached.FetchedAt) < catalogTTL() {
+		catalog = cached
+		return cached.Releases, nil
+	}
+
+	// Offline, any cached catalog is better than none.
+	if Offline {
+		if cached == nil {
+			return nil, fmt.Errorf("no cached list of available versions: %w", ErrOffline)
+		}
+		catalog = cached
+		return cached.Releases, nil
+	}
+
+	header := http.Header{}
+	if cached != nil {
+		if cached.ETag != "" {
+			header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := Get(mirror, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		cached.FetchedAt = time.Now()
+	} else {
+		releases, err := parseReleases(resp)
+		if err != nil {
+			return nil, err
+		}
+		cached = &cachedCatalog{
+			Mirror:       mirror,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+			Releases:     releases,
+		}
+	}
+
+	// Failing to cache the catalog only costs a fetch next time.
+	writeCatalog(cached)
+	catalog = cached
+	Refresh = false
+	return cached.Releases, nil
+}
+
+// releasePattern matches the text of a link to a release, such as terraform_1.5.7 or terraform_1.6.0-beta1.
+// Anything else in a mirror's index, such as terraform_latest, is not a release.
+var releasePattern = regexp.MustCompile(`^terraform_(\d+\.\d+\.\d+(?:-[0-9A-Za-z.]+)?)$`)
+
+// parseReleases reads the versions linked from a release index page.
+func parseReleases(resp *http.Response) ([]string, error) {
+	var releases []string
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return releases, err
+	}
+	doc.Find("a[href]").Each(func(index int, item *goquery.Selection) {
+		if m := releasePattern.FindStringSubmatch(strings.TrimSpace(item.Text())); m != nil {
+			releases = append(releases, m[1])
+		}
+	})
+	return releases, nil
+}
+
+// catalogTTL returns how long a cached catalog stays fresh.
+func catalogTTL() time.Duration {
+	if d, err := time.ParseDuration(ConfigValue("cache_ttl")); err == nil {
+		return d
+	}
+	return defaultCatalogTTL
+}
+
+// catalogFile returns the cache file for the catalog of mirror.
+func catalogFile(mirror string) string {
+	sum := sha256.Sum256([]byte(mirror))
+	return CachePath + string(filepath.Separator) + fmt.Sprintf("catalog-%x.json", sum[:8])
+}
+
+// readCatalog returns the cached catalog of mirror, or nil if there is none.
+func readCatalog(mirror string) *cachedCatalog {
+	if CachePath == "" {
+		return nil
+	}
+
+	raw, err := os.ReadFile(catalogFile(mirror))
+	if err != nil {
+		return nil
+	}
+
+	var cached cachedCatalog
+	if err := json.Unmarshal(raw, &cached); err != nil || cached.Mirror != mirror {
+		return nil
+	}
+	return &cached
+}
+
+// writeCatalog atomically replaces the cached catalog.
+func writeCatalog(cached *cachedCatalog) error {
+	if CachePath == "" {
+		return nil
+	}
+
+	raw, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(CachePath, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(CachePath, ".catalog-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), catalogFile(cached.Mirror))
+}
@@ -0,0 +1,107 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// TestCatalog serves a release index from a local server and tests how the catalog is cached.
+func TestCatalog(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "tfvm-test-helper-catalog")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	requests, fullResponses := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullResponses++
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `<ul><li><a href="/">../terraform</a></li><li><a href="/terraform/latest/">terraform_latest</a></li><li><a href="/terraform/1.5/">terraform_1.5</a></li><li><a href="/terraform/1.5.7/">terraform_1.5.7</a></li><li><a href="/terraform/1.5.6/">terraform_1.5.6</a></li></ul>`)
+	}))
+	defer server.Close()
+
+	os.Setenv("TFVM_MIRROR", server.URL)
+	defer os.Unsetenv("TFVM_MIRROR")
+	CachePath = cacheDir
+	defer func() {
+		CachePath = ""
+		catalog = nil
+	}()
+
+	// Expect the first lookup to fetch the index, skipping links that are not releases, and later lookups to share it.
+	t.Run("fetch once per invocation", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			versions, err := GetAvailableVersions()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(versions) != 2 || versions[0] != "1.5.7" {
+				t.Fatalf("unexpected versions: %v", versions)
+			}
+		}
+		if requests != 1 {
+			t.Fatalf("expected 1 request, made %d", requests)
+		}
+	})
+
+	// Drop the in-memory copy and expect the fresh on-disk copy to be used.
+	t.Run("fresh cache", func(t *testing.T) {
+		catalog = nil
+		if _, err := GetAvailableVersions(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if requests != 1 {
+			t.Fatalf("expected no new request, made %d in total", requests)
+		}
+	})
+
+	// Force a refresh and expect a conditional request that reuses the cached catalog.
+	t.Run("refresh", func(t *testing.T) {
+		Refresh = true
+		versions, err := GetAvailableVersions()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("unexpected versions: %v", versions)
+		}
+		if requests != 2 || fullResponses != 1 {
+			t.Fatalf("expected a conditional request, made %d requests with %d full responses", requests, fullResponses)
+		}
+	})
+
+	// Go offline with a stale cache and expect it to be used without any request.
+	t.Run("offline", func(t *testing.T) {
+		os.Setenv("TFVM_CACHE_TTL", "0s")
+		defer os.Unsetenv("TFVM_CACHE_TTL")
+		Offline = true
+		defer func() { Offline = false }()
+
+		catalog = nil
+		versions, err := GetAvailableVersions()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("unexpected versions: %v", versions)
+		}
+		if requests != 2 {
+			t.Fatalf("expected no new request, made %d in total", requests)
+		}
+
+		if err := Download(server.URL+"/terraform.zip", cacheDir+"/terraform.zip", nil); !errors.Is(err, ErrOffline) {
+			t.Fatalf("expected an offline error, got: %v", err)
+		}
+	})
+}
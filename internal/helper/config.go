@@ -0,0 +1,397 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigPath is the directory the user configuration file is kept in.
+// There is no user configuration when it is empty.
+var ConfigPath string
+
+const (
+	// ConfigFileName is the name of the user configuration file in ConfigPath.
+	ConfigFileName = "config.json"
+
+	// RepoConfigFileName is the name of the configuration file of a repository, found in the working
+	// directory or the nearest parent up to the root of the repository.
+	RepoConfigFileName = ".tfvm.json"
+)
+
+// Where a setting's value comes from, from the highest precedence to the lowest.
+const (
+	ConfigSourceEnv     = "env"
+	ConfigSourceRepo    = "repo"
+	ConfigSourceUser    = "user"
+	ConfigSourceDefault = "default"
+)
+
+// Setting is a configuration key and the environment variable that overrides it.
+type Setting struct {
+	Key         string
+	Env         string
+	Description string
+
+	// Repo reports whether a repository configuration may set it. Where binaries are downloaded from,
+	// which certificates and checksums they are verified with, which versions are removed and which paths are searched can only be
+	// configured by the user, so a cloned repository cannot weaken verification or touch the user's files.
+	Repo bool
+
+	validate func(string) error
+}
+
+// Settings are the settings that can be configured, in the order they are listed.
+var Settings = []Setting{
+	{Key: "mirror", Env: "TFVM_MIRROR", Description: "Base URL of a mirror of the Terraform releases", validate: validateURL},
+	{Key: "verify", Env: "TFVM_VERIFY", Description: "Checksum verification policy: required, optional or off", validate: validateOneOf(VerifyRequired, VerifyOptional, VerifyOff)},
+	{Key: "ca_bundle", Env: "TFVM_CA_BUNDLE", Description: "PEM file of extra CA certificates to trust", validate: validateNotEmpty},
+	{Key: "cache_ttl", Env: "TFVM_CACHE_TTL", Description: "How long the list of available versions is cached", Repo: true, validate: validateDuration},
+	{Key: "auto_install", Env: "TFVM_AUTO_INSTALL", Description: "Install missing versions when running tfvm use", Repo: true, validate: validateBool},
+	{Key: "link_mode", Env: "TFVM_LINK_MODE", Description: "How the active version is linked into bin: hardlink or symlink", Repo: true, validate: validateOneOf(LinkHard, LinkSymbolic)},
+	{Key: "default_version", Env: "TFVM_DEFAULT_VERSION", Description: "Version used and installed when none is given and there is no .tfversion", Repo: true, validate: validateNotEmpty},
+	{Key: "max_versions", Env: "TFVM_MAX_VERSIONS", Description: "Most versions to keep installed", validate: validateCount},
+	{Key: "max_size", Env: "TFVM_MAX_SIZE", Description: "Most disk space for installed versions, such as 2G", validate: validateSize},
+	{Key: "prune_days", Env: "TFVM_PRUNE_DAYS", Description: "Days a version must be unused before tfvm prune removes it", validate: validateCount},
+	{Key: "prune_keep_minor", Env: "TFVM_PRUNE_KEEP_MINOR", Description: "Keep the newest version of each minor line in tfvm prune", validate: validateBool},
+	{Key: "project_roots", Env: "TFVM_PROJECT_ROOTS", Description: "Paths searched for .tfversion files, separated like PATH", validate: validateNotEmpty},
+}
+
+// How the active version is linked into the bin directory.
+const (
+	LinkHard     = "hardlink"
+	LinkSymbolic = "symlink"
+)
+
+// LookupSetting returns the setting named key.
+func LookupSetting(key string) (Setting, bool) {
+	for _, s := range Settings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Validate checks that value can be used for the setting.
+func (s Setting) Validate(value string) error {
+	if err := s.validate(value); err != nil {
+		return fmt.Errorf("invalid %s %q: %w", s.Key, value, err)
+	}
+	return nil
+}
+
+// ConfigFile is a configuration file and the values it sets.
+type ConfigFile struct {
+	Path   string
+	Values map[string]string
+}
+
+// loadedConfig is the user and repository configuration read for a configuration directory and working directory.
+type loadedConfig struct {
+	configPath string
+	cwd        string
+	user       *ConfigFile
+	repo       *ConfigFile
+}
+
+// config is the configuration read by the first lookup, shared by every lookup in this invocation.
+var config *loadedConfig
+
+// configMu guards config against concurrent installs.
+var configMu sync.Mutex
+
+// loadConfig returns the configuration files, reading them again only when ConfigPath or the working directory changed.
+func loadConfig() *loadedConfig {
+	cwd, _ := os.Getwd()
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	if config == nil || config.configPath != ConfigPath || config.cwd != cwd {
+		user, _ := ReadUserConfig()
+		repo, _ := ReadRepoConfig()
+		config = &loadedConfig{configPath: ConfigPath, cwd: cwd, user: user, repo: repo}
+	}
+	return config
+}
+
+// ConfigValue returns the value of the setting named key, which is empty when it is not set.
+// The environment takes precedence over the repository configuration, which takes precedence over
+// the user configuration. Configuration files that cannot be read are ignored; CheckConfig reports them.
+func ConfigValue(key string) string {
+	value, _ := LookupConfig(key)
+	return value
+}
+
+// ConfigBool reports whether the setting named key is set to a true value.
+func ConfigBool(key string) bool {
+	v, err := strconv.ParseBool(ConfigValue(key))
+	return err == nil && v
+}
+
+// LookupConfig returns the value of the setting named key, as ConfigValue does, and where it came from.
+func LookupConfig(key string) (string, string) {
+	s, ok := LookupSetting(key)
+	if !ok {
+		return "", ConfigSourceDefault
+	}
+	if v := os.Getenv(s.Env); v != "" {
+		return v, ConfigSourceEnv
+	}
+	c := loadConfig()
+	if s.Repo && c.repo != nil && c.repo.Values[key] != "" {
+		return c.repo.Values[key], ConfigSourceRepo
+	}
+	if c.user != nil && c.user.Values[key] != "" {
+		return c.user.Values[key], ConfigSourceUser
+	}
+	return "", ConfigSourceDefault
+}
+
+// CheckConfig reads the configuration files and returns the first problem with them.
+func CheckConfig() error {
+	if _, err := ReadUserConfig(); err != nil {
+		return err
+	}
+	_, err := ReadRepoConfig()
+	return err
+}
+
+// UserConfigFile returns the path of the user configuration file, or an empty path if there is none.
+func UserConfigFile() string {
+	if ConfigPath == "" {
+		return ""
+	}
+	return ConfigPath + string(filepath.Separator) + ConfigFileName
+}
+
+// ReadUserConfig returns the user configuration, or nil if there is none.
+func ReadUserConfig() (*ConfigFile, error) {
+	path := UserConfigFile()
+	if path == "" {
+		return nil, nil
+	}
+	return readConfigFile(path, false)
+}
+
+// RepoConfigFile returns the path of the repository configuration file, or an empty path if there is none.
+// It is searched for from the working directory up to the first directory containing .git.
+func RepoConfigFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	for {
+		path := dir + string(filepath.Separator) + RepoConfigFileName
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if _, err := os.Stat(dir + string(filepath.Separator) + ".git"); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ReadRepoConfig returns the repository configuration, or nil if there is none.
+func ReadRepoConfig() (*ConfigFile, error) {
+	path := RepoConfigFile()
+	if path == "" {
+		return nil, nil
+	}
+	return readConfigFile(path, true)
+}
+
+// readConfigFile reads the configuration file at path, which is a JSON object of settings.
+// Values may be strings, numbers or booleans, and project_roots may also be a list of paths.
+func readConfigFile(path string, repo bool) (*ConfigFile, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	c := &ConfigFile{Path: path, Values: map[string]string{}}
+	for key, v := range doc {
+		s, ok := LookupSetting(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown setting %q in %s", key, path)
+		}
+		if repo && !s.Repo {
+			return nil, fmt.Errorf("%s cannot be set in %s, only in the user configuration", key, path)
+		}
+
+		var value string
+		switch v := v.(type) {
+		case string:
+			value = v
+		case bool:
+			value = strconv.FormatBool(v)
+		case json.Number:
+			value = v.String()
+		case []interface{}:
+			if key != "project_roots" {
+				return nil, fmt.Errorf("%s in %s cannot be a list", key, path)
+			}
+			roots := make([]string, len(v))
+			for i, root := range v {
+				if roots[i], ok = root.(string); !ok {
+					return nil, fmt.Errorf("project_roots in %s must be a list of paths", path)
+				}
+			}
+			value = strings.Join(roots, string(filepath.ListSeparator))
+		default:
+			return nil, fmt.Errorf("%s in %s must be a string, number or boolean", key, path)
+		}
+
+		if err := s.Validate(value); err != nil {
+			return nil, fmt.Errorf("%w in %s", err, path)
+		}
+		c.Values[key] = value
+	}
+	return c, nil
+}
+
+// SetConfig sets the setting named key in the configuration file at path, or removes it when value is empty.
+// Other settings in the file are kept.
+func SetConfig(path string, key string, value string) error {
+	s, ok := LookupSetting(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if value != "" {
+		if err := s.Validate(value); err != nil {
+			return err
+		}
+	}
+
+	doc := map[string]interface{}{}
+	raw, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return fmt.Errorf("could not parse %s: %w", path, err)
+		}
+	}
+
+	if value == "" {
+		delete(doc, key)
+	} else {
+		doc[key] = value
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// Temporary files are private, but the configuration is not secret.
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(append(out, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Read the configuration again on the next lookup.
+	configMu.Lock()
+	config = nil
+	configMu.Unlock()
+	return nil
+}
+
+func validateBool(v string) error {
+	_, err := strconv.ParseBool(v)
+	return err
+}
+
+func validateDuration(v string) error {
+	_, err := time.ParseDuration(v)
+	return err
+}
+
+func validateCount(v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 0 {
+		return fmt.Errorf("expected a whole number")
+	}
+	return nil
+}
+
+func validateSize(v string) error {
+	_, err := ParseSize(v)
+	return err
+}
+
+func validateNotEmpty(v string) error {
+	if strings.TrimSpace(v) == "" {
+		return fmt.Errorf("expected a value")
+	}
+	return nil
+}
+
+func validateURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("expected an http or https URL")
+	}
+	return nil
+}
+
+// validateOneOf returns a validation that accepts only the given values.
+func validateOneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, allowed := range values {
+			if v == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %s", strings.Join(values, ", "))
+	}
+}
@@ -0,0 +1,135 @@
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestConfig tests reading settings from the environment and configuration files in order of precedence.
+func TestConfig(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-helper-config")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+	sep := string(filepath.Separator)
+
+	// The repository is a directory with .git, and the working directory is inside it.
+	repoDir := workDir + sep + "repo"
+	cwd := repoDir + sep + "modules" + sep + "network"
+	for _, dir := range []string{repoDir + sep + ".git", cwd} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("cannot create directory: %s", err)
+		}
+	}
+
+	oldCwd, _ := os.Getwd()
+	if err := os.Chdir(cwd); err != nil {
+		t.Fatalf("cannot change directory: %s", err)
+	}
+	defer os.Chdir(oldCwd)
+
+	ConfigPath = workDir + sep + "config"
+	defer func() {
+		ConfigPath = ""
+		config = nil
+	}()
+
+	// Expect settings written to the user configuration to be read back.
+	t.Run("user configuration", func(t *testing.T) {
+		if err := SetConfig(UserConfigFile(), "default_version", "1.6"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := SetConfig(UserConfigFile(), "max_size", "2G"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if value, source := LookupConfig("default_version"); value != "1.6" || source != ConfigSourceUser {
+			t.Fatalf("got %q from %s, expected 1.6 from the user configuration", value, source)
+		}
+	})
+
+	// Expect the configuration to be read once, so that a file written behind its back is not seen.
+	t.Run("read once", func(t *testing.T) {
+		if err := ioutil.WriteFile(repoDir+sep+RepoConfigFileName, []byte(`{"default_version": "1.4"}`), 0644); err != nil {
+			t.Fatalf("cannot write repository configuration: %s", err)
+		}
+		defer os.Remove(repoDir + sep + RepoConfigFileName)
+
+		if value, source := LookupConfig("default_version"); value != "1.6" || source != ConfigSourceUser {
+			t.Fatalf("got %q from %s, expected the configuration read before", value, source)
+		}
+	})
+
+	// Expect the repository configuration, found at the repository root, to take precedence over the user's.
+	t.Run("repository configuration", func(t *testing.T) {
+		raw := `{"default_version": "1.5", "auto_install": true}`
+		if err := ioutil.WriteFile(repoDir+sep+RepoConfigFileName, []byte(raw), 0644); err != nil {
+			t.Fatalf("cannot write repository configuration: %s", err)
+		}
+		config = nil
+
+		if value, source := LookupConfig("default_version"); value != "1.5" || source != ConfigSourceRepo {
+			t.Fatalf("got %q from %s, expected 1.5 from the repository configuration", value, source)
+		}
+		if value := ConfigValue("max_size"); value != "2G" {
+			t.Fatalf("got %q, expected the user configuration to still apply", value)
+		}
+		if !ConfigBool("auto_install") {
+			t.Fatalf("expected a boolean value to be read")
+		}
+	})
+
+	// Expect the environment to take precedence over both files.
+	t.Run("environment", func(t *testing.T) {
+		os.Setenv("TFVM_DEFAULT_VERSION", "1.3")
+		defer os.Unsetenv("TFVM_DEFAULT_VERSION")
+
+		if value, source := LookupConfig("default_version"); value != "1.3" || source != ConfigSourceEnv {
+			t.Fatalf("got %q from %s, expected 1.3 from the environment", value, source)
+		}
+	})
+
+	// Expect a repository configuration that weakens verification, removes versions or searches the filesystem
+	// to be rejected and ignored.
+	t.Run("repository cannot set user settings", func(t *testing.T) {
+		for _, raw := range []string{`{"verify": "off"}`, `{"max_versions": 0}`, `{"project_roots": ["/"]}`, `{"ca_bundle": "ca.pem"}`} {
+			if err := ioutil.WriteFile(repoDir+sep+RepoConfigFileName, []byte(raw), 0644); err != nil {
+				t.Fatalf("cannot write repository configuration: %s", err)
+			}
+			config = nil
+
+			if err := CheckConfig(); err == nil {
+				t.Fatalf("expected an error for %s in the repository configuration", raw)
+			}
+		}
+		if policy := VerifyPolicy(); policy != VerifyRequired {
+			t.Fatalf("got verification policy %q, expected %q", policy, VerifyRequired)
+		}
+		if value := ConfigValue("project_roots"); value != "" {
+			t.Fatalf("got %q, expected project roots to be ignored", value)
+		}
+	})
+
+	// Expect invalid values and unknown settings to be rejected.
+	t.Run("invalid values", func(t *testing.T) {
+		if err := SetConfig(UserConfigFile(), "max_size", "lots"); err == nil {
+			t.Fatalf("expected an error for an invalid size")
+		}
+		if err := SetConfig(UserConfigFile(), "colour", "blue"); err == nil {
+			t.Fatalf("expected an error for an unknown setting")
+		}
+	})
+
+	// Expect an empty value to remove the setting.
+	t.Run("remove setting", func(t *testing.T) {
+		if err := SetConfig(UserConfigFile(), "max_size", ""); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if value := ConfigValue("max_size"); value != "" {
+			t.Fatalf("got %q, expected the setting to be removed", value)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package helper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed Terraform version.
+type version struct {
+	segments   [3]int
+	parts      int
+	prerelease string
+}
+
+// parseVersion parses a version such as 1.5.7, 1.5 or 1.6.0-beta1, with an optional leading v.
+func parseVersion(s string) (version, error) {
+	var v version
+
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		v.prerelease = s[i+1:]
+		s = s[:i]
+	}
+
+	fields := strings.Split(s, ".")
+	if len(fields) < 1 || len(fields) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v.segments[i] = n
+	}
+	v.parts = len(fields)
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 as v is older than, the same as or newer than o.
+func (v version) compare(o version) int {
+	for i := range v.segments {
+		if v.segments[i] != o.segments[i] {
+			if v.segments[i] < o.segments[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// A pre-release is older than the release it precedes.
+	switch {
+	case v.prerelease == o.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case o.prerelease == "":
+		return -1
+	case v.prerelease < o.prerelease:
+		return -1
+	}
+	return 1
+}
+
+// CompareVersions returns -1, 0 or 1 as version a is older than, the same as or newer than b.
+// Versions that cannot be parsed, such as names of linked binaries, sort before all others by name.
+func CompareVersions(a string, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return va.compare(vb)
+}
+
+// constraintTerm is a single operator and version in a constraint.
+type constraintTerm struct {
+	op      string
+	version version
+}
+
+// Constraint is a set of Terraform-style version constraints, such as ">= 1.3, < 1.6" or "~> 1.6",
+// all of which must be met.
+type Constraint []constraintTerm
+
+// constraintOps are the supported operators, longest first so that prefixes match correctly.
+var constraintOps = []string{"~>", ">=", "<=", "!=", ">", "<", "="}
+
+// IsConstraint reports whether spec is a version constraint rather than a plain version.
+func IsConstraint(spec string) bool {
+	return strings.ContainsAny(spec, "<>=!~,")
+}
+
+// ParseConstraint parses a comma separated list of version constraints.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		op := "="
+		for _, candidate := range constraintOps {
+			if strings.HasPrefix(term, candidate) {
+				op = candidate
+				term = strings.TrimSpace(strings.TrimPrefix(term, candidate))
+				break
+			}
+		}
+
+		v, err := parseVersion(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+		}
+		c = append(c, constraintTerm{op: op, version: v})
+	}
+	return c, nil
+}
+
+// Check reports whether the version v meets every term of the constraint.
+func (c Constraint) Check(v string) bool {
+	parsed, err := parseVersion(v)
+	if err != nil {
+		return false
+	}
+
+	for _, term := range c {
+		// Pre-releases only match constraints that name them exactly.
+		if parsed.prerelease != "" && parsed.prerelease != term.version.prerelease {
+			return false
+		}
+		if !term.check(parsed) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t constraintTerm) check(v version) bool {
+	cmp := v.compare(t.version)
+	switch t.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+
+	// ~> allows only the rightmost specified segment to increase.
+	if cmp < 0 {
+		return false
+	}
+	upper := version{parts: 3}
+	last := t.version.parts - 2
+	if last < 0 {
+		last = 0
+	}
+	copy(upper.segments[:], t.version.segments[:last])
+	upper.segments[last] = t.version.segments[last] + 1
+	return v.compare(upper) < 0
+}
+
+// MinorLine returns the minor release line of version v, such as 1.5 for 1.5.7.
+func MinorLine(v string) (string, error) {
+	parsed, err := parseVersion(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", parsed.segments[0], parsed.segments[1]), nil
+}
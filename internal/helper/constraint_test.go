@@ -0,0 +1,67 @@
+package helper
+
+import "testing"
+
+// TestConstraint tests matching versions against Terraform-style constraints.
+func TestConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"1.5.7", "1.5.7", true},
+		{"= 1.5.7", "1.5.6", false},
+		{"!= 1.5.7", "1.5.6", true},
+		{"> 1.5", "1.5.1", true},
+		{">= 1.3, < 1.6", "1.5.7", true},
+		{">= 1.3, < 1.6", "1.6.0", false},
+		{"< 1.0", "0.15.5", true},
+		{"~> 1.6", "1.9.2", true},
+		{"~> 1.6", "2.0.0", false},
+		{"~> 1.6", "1.5.7", false},
+		{"~> 1.6.3", "1.6.9", true},
+		{"~> 1.6.3", "1.7.0", false},
+		{"~> 1", "1.9.0", true},
+		{">= 1.6", "1.7.0-beta1", false},
+		{"1.7.0-beta1", "1.7.0-beta1", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.constraint+" "+tc.version, func(t *testing.T) {
+			c, err := ParseConstraint(tc.constraint)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := c.Check(tc.version); got != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, got)
+			}
+		})
+	}
+
+	// Expect invalid constraints to be rejected.
+	t.Run("invalid constraint", func(t *testing.T) {
+		if _, err := ParseConstraint(">= one"); err == nil {
+			t.Fatalf("unexpectedly parsed an invalid constraint")
+		}
+	})
+}
+
+// TestCompareVersions tests ordering versions.
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.5.7", "1.5.7", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"0.15.5", "1.0.0", -1},
+		{"1.7.0-beta1", "1.7.0", -1},
+		{"dev", "1.0.0", -1},
+	}
+
+	for _, tc := range cases {
+		if got := CompareVersions(tc.a, tc.b); got != tc.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tc.a, tc.b, got, tc.expected)
+		}
+	}
+}
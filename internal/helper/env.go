@@ -0,0 +1,34 @@
+package helper
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvBool reports whether the named environment variable is set to a true value.
+func EnvBool(name string) bool {
+	v, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && v
+}
+
+// ParseSize parses a size in bytes with an optional unit, such as 1048576, 500M, 500MB or 2GiB.
+// Units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	upper := strings.ToUpper(s)
+	upper = strings.TrimSuffix(strings.TrimSuffix(upper, "IB"), "B")
+
+	multiplier := int64(1)
+	if i := strings.IndexAny(upper, "KMGT"); i >= 0 && i == len(upper)-1 {
+		multiplier = int64(1) << (10 * (strings.IndexByte("KMGT", upper[i]) + 1))
+		upper = upper[:i]
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
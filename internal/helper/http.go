@@ -0,0 +1,337 @@
+package helper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// connectTimeout bounds establishing a connection, including the TLS handshake.
+	connectTimeout = 10 * time.Second
+
+	// readTimeout bounds how long a response may go without sending any data.
+	readTimeout = 30 * time.Second
+
+	// maxAttempts is how many times a request is tried before giving up.
+	maxAttempts = 4
+)
+
+// retryDelay is the delay before the first retry, doubled on each further attempt.
+var retryDelay = time.Second
+
+// errReadTimeout is returned when a response stalls for longer than readTimeout.
+var errReadTimeout = errors.New("timed out reading response")
+
+// Offline disables all network access, so that anything needing it fails immediately.
+var Offline bool
+
+// ErrOffline is returned for operations that need the network while Offline is set.
+var ErrOffline = errors.New("network access is disabled in offline mode")
+
+// defaultMirror is where Terraform releases are downloaded from unless TFVM_MIRROR is set.
+const defaultMirror = "https://releases.hashicorp.com/terraform/"
+
+var (
+	clientOnce sync.Once
+	client     *http.Client
+	clientErr  error
+)
+
+// MirrorURL returns the base URL of the Terraform release index, ending in a slash.
+func MirrorURL() string {
+	mirror := ConfigValue("mirror")
+	if mirror == "" {
+		return defaultMirror
+	}
+	return strings.TrimSuffix(mirror, "/") + "/"
+}
+
+// httpClient returns the client shared by every request tfvm makes.
+// Proxies are taken from HTTPS_PROXY, HTTP_PROXY and NO_PROXY, and the ca_bundle setting
+// names a PEM file of certificates to trust in addition to the system roots.
+func httpClient() (*http.Client, error) {
+	clientOnce.Do(func() {
+		transport := &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   connectTimeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   connectTimeout,
+			ResponseHeaderTimeout: readTimeout,
+			IdleConnTimeout:       90 * time.Second,
+		}
+
+		if bundle := ConfigValue("ca_bundle"); bundle != "" {
+			pool, err := loadCABundle(bundle)
+			if err != nil {
+				clientErr = err
+				return
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		}
+
+		client = &http.Client{Transport: transport}
+	})
+	return client, clientErr
+}
+
+// loadCABundle returns the system roots together with the certificates in the PEM file at path.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// authorize adds credentials for the request's host.
+// A token in TFVM_MIRROR_TOKEN is sent to a configured mirror, never to the default one,
+// otherwise a matching ~/.netrc entry is used.
+func authorize(req *http.Request) {
+	if token := os.Getenv("TFVM_MIRROR_TOKEN"); token != "" && ConfigValue("mirror") != "" {
+		if mirror, err := url.Parse(MirrorURL()); err == nil && mirror.Host == req.URL.Host {
+			req.Header.Set("Authorization", "Bearer "+token)
+			return
+		}
+	}
+
+	if login, password, ok := netrcCredentials(req.URL.Hostname()); ok {
+		req.SetBasicAuth(login, password)
+	}
+}
+
+// Get requests url with any extra headers, retrying transient failures, and returns the response.
+// Responses other than 2xx or 304 Not Modified are returned as errors.
+func Get(url string, header http.Header) (*http.Response, error) {
+	var resp *http.Response
+	err := retry(func() error {
+		var err error
+		resp, err = do(url, header)
+		if err != nil {
+			return err
+		}
+		if (resp.StatusCode < 200 || resp.StatusCode > 299) && resp.StatusCode != http.StatusNotModified {
+			resp.Body.Close()
+			return statusError(url, resp)
+		}
+		return nil
+	})
+	return resp, err
+}
+
+// ProgressFunc is called as a download advances with the bytes received so far and the
+// total size, which is -1 until known when the server does not report it.
+type ProgressFunc func(done int64, total int64)
+
+// Download saves url to dest, retrying transient failures, and reports to progress if it is not nil.
+// A partial file left at dest by an earlier attempt is resumed when the server supports it.
+func Download(url string, dest string, progress ProgressFunc) error {
+	return retry(func() error {
+		return downloadOnce(url, dest, progress)
+	})
+}
+
+// downloadOnce makes a single attempt at saving url to dest.
+func downloadOnce(url string, dest string, progress ProgressFunc) error {
+	var offset int64
+	if info, err := os.Stat(dest); err == nil {
+		offset = info.Size()
+	}
+
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := do(url, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is already complete.
+		return nil
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		flags |= os.O_TRUNC
+		offset = 0
+	default:
+		return statusError(url, resp)
+	}
+
+	out, err := os.OpenFile(dest, flags, 0644)
+	if err != nil {
+		return permanent{err}
+	}
+	defer out.Close()
+
+	var w io.Writer = out
+	if progress != nil {
+		total := int64(-1)
+		if resp.ContentLength >= 0 {
+			total = offset + resp.ContentLength
+		}
+		w = &progressWriter{w: out, done: offset, total: total, progress: progress}
+		progress(offset, total)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err == nil && progress != nil && resp.ContentLength < 0 {
+		// Report completion once the size is known.
+		progress(offset+n, offset+n)
+	}
+	return err
+}
+
+// progressWriter reports the bytes written through it.
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	p.progress(p.done, p.total)
+	return n, err
+}
+
+// do sends a single GET request for url with any extra headers.
+// The response body fails with errReadTimeout if it stalls for longer than readTimeout.
+func do(url string, header http.Header) (*http.Response, error) {
+	if Offline {
+		return nil, permanent{fmt.Errorf("cannot fetch %s: %w", url, ErrOffline)}
+	}
+
+	client, err := httpClient()
+	if err != nil {
+		return nil, permanent{err}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, permanent{err}
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	authorize(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+
+		// A host that does not exist will not appear on retry.
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, permanent{err}
+		}
+		return nil, err
+	}
+
+	body := &idleTimeoutReader{r: resp.Body, cancel: cancel}
+	body.timer = time.AfterFunc(readTimeout, body.expire)
+	resp.Body = body
+	return resp, nil
+}
+
+// retry runs fn until it succeeds, fails permanently or runs out of attempts.
+func retry(fn func() error) error {
+	delay := retryDelay
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		var p permanent
+		if errors.As(err, &p) {
+			return p.err
+		}
+
+		if attempt < maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
+
+// statusError describes a non-2xx response, which is permanent unless the server may recover.
+func statusError(url string, resp *http.Response) error {
+	err := fmt.Errorf("request to %s failed: server responded %s", url, resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		return err
+	}
+	return permanent{err}
+}
+
+// permanent wraps errors that should not be retried.
+type permanent struct {
+	err error
+}
+
+func (p permanent) Error() string {
+	return p.err.Error()
+}
+
+// idleTimeoutReader cancels a response that stops sending data.
+type idleTimeoutReader struct {
+	r       io.ReadCloser
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	expired int32
+}
+
+func (t *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if atomic.LoadInt32(&t.expired) == 1 {
+		return n, errReadTimeout
+	}
+	t.timer.Reset(readTimeout)
+	return n, err
+}
+
+func (t *idleTimeoutReader) Close() error {
+	t.timer.Stop()
+	t.cancel()
+	return t.r.Close()
+}
+
+func (t *idleTimeoutReader) expire() {
+	atomic.StoreInt32(&t.expired, 1)
+	t.cancel()
+}
@@ -0,0 +1,137 @@
+package helper
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDownload serves archives from a local server and tests various Download cases.
+func TestDownload(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "tfvm-test-helper-download")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	retryDelay = time.Millisecond
+	content := bytes.Repeat([]byte("terraform"), 1024)
+
+	failures := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/archive.zip", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(content))
+	})
+	mux.HandleFunc("/flaky.zip", func(w http.ResponseWriter, r *http.Request) {
+		if failures < 2 {
+			failures++
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "flaky.zip", time.Time{}, bytes.NewReader(content))
+	})
+	mux.HandleFunc("/private.zip", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "private.zip", time.Time{}, bytes.NewReader(content))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Download a complete archive and expect identical content.
+	t.Run("complete download", func(t *testing.T) {
+		dest := workDir + string(filepath.Separator) + "complete.zip"
+		var done, total int64
+		progress := func(d int64, t int64) {
+			done, total = d, t
+		}
+		if err := Download(server.URL+"/archive.zip", dest, progress); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if got, _ := ioutil.ReadFile(dest); !bytes.Equal(got, content) {
+			t.Fatalf("downloaded %d bytes, expected %d", len(got), len(content))
+		}
+
+		if done != int64(len(content)) || total != int64(len(content)) {
+			t.Fatalf("reported %d of %d bytes, expected %d", done, total, len(content))
+		}
+	})
+
+	// Start from a partial file and expect the rest to be appended.
+	t.Run("resumed download", func(t *testing.T) {
+		dest := workDir + string(filepath.Separator) + "partial.zip"
+		if err := ioutil.WriteFile(dest, content[:100], 0644); err != nil {
+			t.Fatalf("cannot create partial file: %s", err)
+		}
+
+		if err := Download(server.URL+"/archive.zip", dest, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if got, _ := ioutil.ReadFile(dest); !bytes.Equal(got, content) {
+			t.Fatalf("downloaded %d bytes, expected %d", len(got), len(content))
+		}
+	})
+
+	// Fail twice with a transient status and expect the download to be retried.
+	t.Run("transient failure", func(t *testing.T) {
+		dest := workDir + string(filepath.Separator) + "flaky.zip"
+		if err := Download(server.URL+"/flaky.zip", dest, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	// Download from a mirror that needs a token and expect it to be sent.
+	t.Run("authenticated mirror", func(t *testing.T) {
+		dest := workDir + string(filepath.Separator) + "private.zip"
+		if err := Download(server.URL+"/private.zip", dest, nil); err == nil {
+			t.Fatalf("unexpectedly downloaded without credentials")
+		}
+
+		os.Setenv("TFVM_MIRROR", server.URL)
+		os.Setenv("TFVM_MIRROR_TOKEN", "token")
+		defer os.Unsetenv("TFVM_MIRROR")
+		defer os.Unsetenv("TFVM_MIRROR_TOKEN")
+
+		if err := Download(server.URL+"/private.zip", dest, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	// Expect the mirror token not to be sent to the default mirror when no mirror is configured.
+	t.Run("default mirror", func(t *testing.T) {
+		os.Setenv("TFVM_MIRROR_TOKEN", "token")
+		defer os.Unsetenv("TFVM_MIRROR_TOKEN")
+
+		req, err := http.NewRequest(http.MethodGet, MirrorURL()+"/1.5.7/", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		authorize(req)
+		if auth := req.Header.Get("Authorization"); auth != "" {
+			t.Fatalf("unexpectedly sent %q to %s", auth, req.URL.Host)
+		}
+	})
+
+	// Request a missing archive and expect a readable error instead of a saved error page.
+	t.Run("missing archive", func(t *testing.T) {
+		dest := workDir + string(filepath.Separator) + "missing.zip"
+		err := Download(server.URL+"/missing.zip", dest, nil)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Fatalf("expected a 404 error, got: %v", err)
+		}
+
+		if _, err := os.Stat(dest); !os.IsNotExist(err) {
+			t.Fatalf("unexpectedly saved the error response")
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// lockFile is the name of the advisory lock file kept in the install path.
+const lockFile = ".lock"
+
+// defaultLockTimeout is how long to wait for a lock before giving up.
+// It can be overridden with TFVM_LOCK_TIMEOUT.
+const defaultLockTimeout = 30 * time.Second
+
+// errLocked is returned by tryLock when another process holds a conflicting lock.
+var errLocked = errors.New("lock is held by another process")
+
+// Lock is an advisory lock on the tfvm store.
+type Lock struct {
+	f *os.File
+}
+
+// LockShared takes a shared lock on the store at installPath for reading.
+// A store that does not exist yet has nothing to read, so it is not created.
+func LockShared(installPath string) (*Lock, error) {
+	if _, err := os.Stat(installPath); os.IsNotExist(err) {
+		return &Lock{}, nil
+	}
+	return acquireLock(installPath, false)
+}
+
+// LockExclusive takes an exclusive lock on the store at installPath for installing, removing or switching versions,
+// creating the store if needed.
+func LockExclusive(installPath string) (*Lock, error) {
+	if err := os.MkdirAll(installPath, 0755); err != nil {
+		return nil, err
+	}
+	return acquireLock(installPath, true)
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	if l.f == nil {
+		return nil
+	}
+	err := unlockFile(l.f)
+	l.f.Close()
+	return err
+}
+
+// acquireLock waits until the lock can be taken or the timeout expires.
+func acquireLock(installPath string, exclusive bool) (*Lock, error) {
+	path := installPath + string(filepath.Separator) + lockFile
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultLockTimeout
+	if d, err := time.ParseDuration(os.Getenv("TFVM_LOCK_TIMEOUT")); err == nil {
+		timeout = d
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err = tryLock(f, exclusive)
+		if err == nil {
+			break
+		}
+		if err != errLocked || time.Now().After(deadline) {
+			holder := lockHolder(f)
+			f.Close()
+			if err == errLocked {
+				return nil, fmt.Errorf("timed out after %s waiting for %s, held by %s", timeout, path, holder)
+			}
+			return nil, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Record who holds the lock so that waiting processes can report it.
+	if exclusive {
+		f.Truncate(0)
+		f.WriteAt([]byte(fmt.Sprintf("%d %s", os.Getpid(), strings.Join(os.Args, " "))), 0)
+	}
+
+	return &Lock{f: f}, nil
+}
+
+// lockHolder describes the process recorded in the lock file.
+func lockHolder(f *os.File) string {
+	raw, err := io.ReadAll(io.NewSectionReader(f, 0, 1024))
+	if err != nil || len(raw) == 0 {
+		return "another process"
+	}
+
+	fields := strings.SplitN(string(raw), " ", 2)
+	if len(fields) < 2 {
+		return "process " + fields[0]
+	}
+	return fmt.Sprintf("process %s (%s)", fields[0], fields[1])
+}
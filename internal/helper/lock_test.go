@@ -0,0 +1,53 @@
+package helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestLock checks that shared locks coexist and that exclusive locks time out naming the holder.
+func TestLock(t *testing.T) {
+	installDir, err := ioutil.TempDir("", "tfvm-test-helper-lock")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(installDir)
+
+	os.Setenv("TFVM_LOCK_TIMEOUT", "200ms")
+	defer os.Unsetenv("TFVM_LOCK_TIMEOUT")
+
+	// Take two shared locks and expect both to succeed.
+	first, err := LockShared(installDir)
+	if err != nil {
+		t.Fatalf("cannot take shared lock: %s", err)
+	}
+	second, err := LockShared(installDir)
+	if err != nil {
+		t.Fatalf("cannot take second shared lock: %s", err)
+	}
+
+	// Expect an exclusive lock to wait for the shared locks.
+	if _, err := LockExclusive(installDir); err == nil {
+		t.Fatalf("unexpectedly took an exclusive lock while shared locks were held")
+	}
+	first.Unlock()
+	second.Unlock()
+
+	// Take an exclusive lock and expect waiters to time out naming this process.
+	lock, err := LockExclusive(installDir)
+	if err != nil {
+		t.Fatalf("cannot take exclusive lock: %s", err)
+	}
+	defer lock.Unlock()
+
+	_, err = LockShared(installDir)
+	if err == nil {
+		t.Fatalf("unexpectedly took a shared lock while an exclusive lock was held")
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("process %d", os.Getpid())) {
+		t.Fatalf("expected the error to name the holding process: %s", err)
+	}
+}
@@ -0,0 +1,211 @@
+package helper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// manifestFile is the name of the manifest kept in each version directory.
+const manifestFile = "manifest.json"
+
+// Sources a version can be installed from.
+const (
+	SourceMirror   = "mirror"
+	SourceFile     = "file"
+	SourceURL      = "url"
+	SourceLinked   = "linked"
+	SourceMigrated = "migrated"
+)
+
+// Manifest describes an installed version of Terraform.
+type Manifest struct {
+	Version     string     `json:"version"`
+	Source      string     `json:"source"`
+	URL         string     `json:"url,omitempty"`
+	SHA256      string     `json:"sha256,omitempty"`
+	Verified    bool       `json:"verified"`
+	InstalledAt time.Time  `json:"installed_at"`
+	Platform    string     `json:"platform"`
+	Path        string     `json:"path,omitempty"`
+	LastUsed    *time.Time `json:"last_used,omitempty"`
+}
+
+// NewManifest returns a manifest for version installed now on this platform from source.
+func NewManifest(version string, source string) *Manifest {
+	return &Manifest{
+		Version:     version,
+		Source:      source,
+		InstalledAt: time.Now().UTC(),
+		Platform:    runtime.GOOS + "_" + runtime.GOARCH,
+	}
+}
+
+// VersionDir returns the directory version is installed in.
+func VersionDir(installPath string, version string) string {
+	return installPath + string(filepath.Separator) + version
+}
+
+// BinaryPath returns the Terraform binary of an installed version,
+// which for linked binaries is the external file they were registered with.
+func BinaryPath(installPath string, extension string, version string) string {
+	if target, ok := LinkedVersion(installPath, extension, version); ok {
+		return target
+	}
+	return VersionDir(installPath, version) + string(filepath.Separator) + "terraform" + extension
+}
+
+// ReadManifest reads the manifest of an installed version.
+func ReadManifest(installPath string, version string) (*Manifest, error) {
+	raw, err := os.ReadFile(VersionDir(installPath, version) + string(filepath.Separator) + manifestFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// WriteManifest atomically writes m into the version directory dir.
+func WriteManifest(dir string, m *Manifest) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".manifest-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dir+string(filepath.Separator)+manifestFile)
+}
+
+// RecordUse stores the current time as the last time version was used.
+func RecordUse(installPath string, version string) error {
+	m, err := ReadManifest(installPath, version)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	m.LastUsed = &now
+	return WriteManifest(VersionDir(installPath, version), m)
+}
+
+// VersionSize returns the disk space used by an installed version.
+// The size of a linked version is the size of the binary it points at.
+func VersionSize(installPath string, extension string, version string) (int64, error) {
+	if target, ok := LinkedVersion(installPath, extension, version); ok {
+		info, err := os.Stat(target)
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+
+	var size int64
+	err := filepath.WalkDir(VersionDir(installPath, version), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// MigrateLayout moves versions installed by older versions of tfvm as flat terraform<version> files
+// into their own directories with a manifest. Stray license files from old archives are removed.
+func MigrateLayout(installPath string, extension string) error {
+	entries, err := os.ReadDir(installPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var flat []os.DirEntry
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "terraform") || e.Name() == "LICENSE.txt" {
+			flat = append(flat, e)
+		}
+	}
+	if len(flat) == 0 {
+		return nil
+	}
+
+	lock, err := LockExclusive(installPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	for _, e := range flat {
+		path := installPath + string(filepath.Separator) + e.Name()
+		version := strings.TrimSuffix(strings.TrimPrefix(e.Name(), "terraform"), extension)
+		if e.Name() == "LICENSE.txt" || version == "" {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := migrateVersion(installPath, extension, version, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateVersion moves the flat binary at path into a directory for version.
+func migrateVersion(installPath string, extension string, version string, path string) error {
+	staging, err := NewStagingDir(installPath)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	m := NewManifest(version, SourceMigrated)
+	if info, err := os.Lstat(path); err == nil {
+		m.InstalledAt = info.ModTime().UTC()
+	}
+	if target, err := os.Readlink(path); err == nil {
+		m.Source = SourceLinked
+		m.Path = target
+	} else if err := os.Rename(path, staging+string(filepath.Separator)+"terraform"+extension); err != nil {
+		return err
+	}
+
+	if err := WriteManifest(staging, m); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, VersionDir(installPath, version)); err != nil {
+		return err
+	}
+	if m.Source == SourceLinked {
+		return os.Remove(path)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestMigrateLayout sets up a flat install path and tests that it is moved into version directories.
+func TestMigrateLayout(t *testing.T) {
+	installDir, err := ioutil.TempDir("", "tfvm-test-helper-manifest")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(installDir)
+
+	for _, name := range []string{"terraform1.5.7", "terraform0.15.0", "LICENSE.txt", ".lock"} {
+		if err := ioutil.WriteFile(installDir+string(filepath.Separator)+name, []byte(name), 0755); err != nil {
+			t.Fatalf("cannot create stub file: %s", err)
+		}
+	}
+	custom := installDir + string(filepath.Separator) + ".custom"
+	if err := ioutil.WriteFile(custom, nil, 0755); err != nil {
+		t.Fatalf("cannot create stub binary: %s", err)
+	}
+	if err := os.Symlink(custom, installDir+string(filepath.Separator)+"terraformdev"); err != nil {
+		t.Fatalf("cannot create stub link: %s", err)
+	}
+
+	// Migrate the flat layout and expect every version to have a directory and manifest.
+	t.Run("migrate flat layout", func(t *testing.T) {
+		if err := MigrateLayout(installDir, ""); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		versions, err := GetInstalledVersions(installDir, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if want := []string{"dev", "0.15.0", "1.5.7"}; !reflect.DeepEqual(versions, want) {
+			t.Fatalf("expected versions %v, got %v", want, versions)
+		}
+
+		raw, err := ioutil.ReadFile(BinaryPath(installDir, "", "1.5.7"))
+		if err != nil || string(raw) != "terraform1.5.7" {
+			t.Fatalf("expected the binary to be moved into its version directory: %v", err)
+		}
+
+		if target, ok := LinkedVersion(installDir, "", "dev"); !ok || target != custom {
+			t.Fatalf("expected dev to stay linked to %s, got %q", custom, target)
+		}
+
+		if _, err := os.Stat(installDir + string(filepath.Separator) + "LICENSE.txt"); !os.IsNotExist(err) {
+			t.Fatalf("expected the stray license file to be removed")
+		}
+	})
+
+	// Migrate again and expect nothing to change.
+	t.Run("migrate twice", func(t *testing.T) {
+		if err := MigrateLayout(installDir, ""); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		m, err := ReadManifest(installDir, "1.5.7")
+		if err != nil || m.Source != SourceMigrated {
+			t.Fatalf("expected the migrated manifest to be kept, got %+v (%v)", m, err)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// netrcCredentials returns the login and password for host from the netrc file,
+// which is $NETRC if set and ~/.netrc (or ~/_netrc on Windows) otherwise.
+func netrcCredentials(host string) (string, string, bool) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+		path = home + string(filepath.Separator) + ".netrc"
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			path = home + string(filepath.Separator) + "_netrc"
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+	return parseNetrc(string(raw), host)
+}
+
+// netrcEntry is a machine or default entry in a netrc file.
+type netrcEntry struct {
+	machine   string
+	isDefault bool
+	login     string
+	password  string
+}
+
+// parseNetrc finds the credentials for host in the contents of a netrc file,
+// falling back to the default entry.
+func parseNetrc(data string, host string) (string, string, bool) {
+	var entries []netrcEntry
+
+	fields := strings.Fields(data)
+parse:
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			entries = append(entries, netrcEntry{})
+			if i+1 < len(fields) {
+				i++
+				entries[len(entries)-1].machine = fields[i]
+			}
+		case "default":
+			entries = append(entries, netrcEntry{isDefault: true})
+		case "login", "password":
+			if len(entries) == 0 || i+1 >= len(fields) {
+				i++
+				continue
+			}
+			i++
+			if fields[i-1] == "login" {
+				entries[len(entries)-1].login = fields[i]
+			} else {
+				entries[len(entries)-1].password = fields[i]
+			}
+		case "macdef":
+			// Macro definitions are free text, and by convention come last.
+			break parse
+		}
+	}
+
+	for _, e := range entries {
+		if !e.isDefault && e.machine == host {
+			return e.login, e.password, true
+		}
+	}
+	for _, e := range entries {
+		if e.isDefault {
+			return e.login, e.password, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,41 @@
+package helper
+
+import "testing"
+
+// TestParseNetrc tests finding credentials in netrc contents.
+func TestParseNetrc(t *testing.T) {
+	data := `
+machine releases.example.com
+	login alice
+	password secret
+machine other.example.com login bob password hunter2
+default login anonymous password guest
+`
+
+	cases := []struct {
+		name     string
+		host     string
+		login    string
+		password string
+	}{
+		{"multi-line entry", "releases.example.com", "alice", "secret"},
+		{"single-line entry", "other.example.com", "bob", "hunter2"},
+		{"default entry", "unknown.example.com", "anonymous", "guest"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			login, password, ok := parseNetrc(data, tc.host)
+			if !ok || login != tc.login || password != tc.password {
+				t.Fatalf("got %q/%q (%t), expected %q/%q", login, password, ok, tc.login, tc.password)
+			}
+		})
+	}
+
+	// Expect no credentials without a matching or default entry.
+	t.Run("no match", func(t *testing.T) {
+		if _, _, ok := parseNetrc("machine releases.example.com login alice", "unknown.example.com"); ok {
+			t.Fatalf("unexpectedly found credentials")
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Paths are the directories tfvm keeps its files in.
+// Data holds the versions and bin directories, Cache the release catalog and archives,
+// and Config the configuration files. They are the same directory unless the XDG layout is used.
+type Paths struct {
+	Data   string
+	Cache  string
+	Config string
+
+	// Legacy is the ~/.tfvm directory to migrate into Data, if there is one.
+	Legacy string
+}
+
+// ResolvePaths returns the directories tfvm uses. TFVM_HOME relocates everything into one directory.
+// Otherwise Linux uses the XDG base directories, and other platforms use ~/.tfvm.
+func ResolvePaths() (Paths, error) {
+	if home := os.Getenv("TFVM_HOME"); home != "" {
+		home, err := filepath.Abs(home)
+		if err != nil {
+			return Paths{}, err
+		}
+		return Paths{Data: home, Cache: home + string(filepath.Separator) + "cache", Config: home}, nil
+	}
+
+	home, homeErr := os.UserHomeDir()
+	if runtime.GOOS != "linux" {
+		if homeErr != nil {
+			return Paths{}, fmt.Errorf("%w, set TFVM_HOME to choose where tfvm keeps its files", homeErr)
+		}
+		base := home + string(filepath.Separator) + ".tfvm"
+		return Paths{Data: base, Cache: base + string(filepath.Separator) + "cache", Config: base}, nil
+	}
+
+	data, err := xdgDir("XDG_DATA_HOME", home, ".local/share")
+	if err == nil {
+		var p Paths
+		p.Data = data
+		if p.Cache, err = xdgDir("XDG_CACHE_HOME", home, ".cache"); err == nil {
+			p.Config, err = xdgDir("XDG_CONFIG_HOME", home, ".config")
+		}
+		if err == nil {
+			if homeErr == nil {
+				p.Legacy = legacyDir(home)
+			}
+			return p, nil
+		}
+	}
+	if homeErr != nil {
+		return Paths{}, fmt.Errorf("%w, set TFVM_HOME or the XDG base directories to choose where tfvm keeps its files", homeErr)
+	}
+	return Paths{}, err
+}
+
+// xdgDir returns the tfvm directory under the XDG base directory named by env,
+// which defaults to fallback under home when it is unset or not absolute, as the specification requires.
+func xdgDir(env string, home string, fallback string) (string, error) {
+	base := os.Getenv(env)
+	if !filepath.IsAbs(base) {
+		if home == "" {
+			return "", fmt.Errorf("%s is not set", env)
+		}
+		base = home + string(filepath.Separator) + filepath.FromSlash(fallback)
+	}
+	return base + string(filepath.Separator) + "tfvm", nil
+}
+
+// legacyDir returns ~/.tfvm if it is a real directory. Once migrated it is left as a link to the new location.
+func legacyDir(home string) string {
+	legacy := home + string(filepath.Separator) + ".tfvm"
+	info, err := os.Lstat(legacy)
+	if err != nil || !info.IsDir() {
+		return ""
+	}
+	return legacy
+}
+
+// MigrateLegacy moves ~/.tfvm to the XDG data directory, and its cache to the XDG cache directory.
+// ~/.tfvm is replaced by a link to the data directory, so a PATH that contains ~/.tfvm/bin keeps working.
+// It returns false with no error when there was nothing to migrate. On failure the caller should keep
+// using the legacy directory, which is left in place as long as it could not be moved.
+func MigrateLegacy(p Paths) (bool, error) {
+	if p.Legacy == "" {
+		return false, nil
+	}
+	if _, err := os.Stat(p.Data); err == nil {
+		return false, fmt.Errorf("both %s and %s exist, remove one of them", p.Legacy, p.Data)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.Data), 0755); err != nil {
+		return false, err
+	}
+	if err := os.Rename(p.Legacy, p.Data); err != nil {
+		// Another tfvm process may have migrated it first.
+		if _, statErr := os.Stat(p.Data); statErr == nil && legacyDir(filepath.Dir(p.Legacy)) != p.Legacy {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// The cache can always be downloaded again, so it is dropped if it cannot be moved.
+	oldCache := p.Data + string(filepath.Separator) + "cache"
+	if oldCache != p.Cache {
+		if _, err := os.Stat(oldCache); err == nil {
+			err := os.MkdirAll(filepath.Dir(p.Cache), 0755)
+			if err == nil {
+				err = os.Rename(oldCache, p.Cache)
+			}
+			if err != nil {
+				os.RemoveAll(oldCache)
+			}
+		}
+	}
+
+	if err := os.Symlink(p.Data, p.Legacy); err != nil && !errors.Is(err, os.ErrExist) {
+		return true, fmt.Errorf("moved to %s but could not link %s to it: %w", p.Data, p.Legacy, err)
+	}
+	return true, nil
+}
+
+// LegacyPaths returns the directories of the legacy ~/.tfvm layout, for when it could not be migrated.
+func (p Paths) LegacyPaths() Paths {
+	return Paths{Data: p.Legacy, Cache: p.Legacy + string(filepath.Separator) + "cache", Config: p.Legacy}
+}
@@ -0,0 +1,102 @@
+package helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestResolvePaths tests choosing the tfvm directories and migrating ~/.tfvm to the XDG layout.
+func TestResolvePaths(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the XDG layout is only used on Linux")
+	}
+
+	home, err := ioutil.TempDir("", "tfvm-test-helper-paths")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(home)
+
+	for _, env := range []string{"HOME", "TFVM_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_CONFIG_HOME"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Unsetenv(env)
+	}
+	os.Setenv("HOME", home)
+	sep := string(filepath.Separator)
+
+	// Expect every directory under TFVM_HOME when it is set.
+	t.Run("tfvm home", func(t *testing.T) {
+		os.Setenv("TFVM_HOME", home+sep+"store")
+		defer os.Unsetenv("TFVM_HOME")
+
+		p, err := ResolvePaths()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if p.Data != home+sep+"store" || p.Cache != home+sep+"store"+sep+"cache" || p.Config != home+sep+"store" || p.Legacy != "" {
+			t.Fatalf("unexpected paths %+v", p)
+		}
+	})
+
+	// Expect the XDG defaults, with the data directory following XDG_DATA_HOME.
+	t.Run("xdg defaults", func(t *testing.T) {
+		os.Setenv("XDG_DATA_HOME", home+sep+"data")
+		defer os.Unsetenv("XDG_DATA_HOME")
+
+		p, err := ResolvePaths()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if p.Data != home+sep+"data"+sep+"tfvm" || p.Cache != home+sep+".cache"+sep+"tfvm" || p.Config != home+sep+".config"+sep+"tfvm" {
+			t.Fatalf("unexpected paths %+v", p)
+		}
+	})
+
+	// Expect an error with neither a home directory nor an override.
+	t.Run("no home", func(t *testing.T) {
+		os.Unsetenv("HOME")
+		defer os.Setenv("HOME", home)
+
+		if _, err := ResolvePaths(); err == nil {
+			t.Fatalf("expected an error without a home directory")
+		}
+	})
+
+	// Expect ~/.tfvm to be moved to the data directory, its cache to the cache directory, and a link left behind.
+	t.Run("migrate legacy directory", func(t *testing.T) {
+		legacy := home + sep + ".tfvm"
+		for _, dir := range []string{"versions" + sep + "1.5.7", "bin", "cache"} {
+			if err := os.MkdirAll(legacy+sep+dir, 0755); err != nil {
+				t.Fatalf("cannot create legacy directory: %s", err)
+			}
+		}
+
+		p, err := ResolvePaths()
+		if err != nil || p.Legacy != legacy {
+			t.Fatalf("expected the legacy directory to be found, got %+v (%v)", p, err)
+		}
+
+		migrated, err := MigrateLegacy(p)
+		if err != nil || !migrated {
+			t.Fatalf("expected the legacy directory to be migrated, got %t (%v)", migrated, err)
+		}
+
+		if _, err := os.Stat(p.Data + sep + "versions" + sep + "1.5.7"); err != nil {
+			t.Fatalf("expected the versions to be moved: %s", err)
+		}
+		if _, err := os.Stat(p.Cache); err != nil {
+			t.Fatalf("expected the cache to be moved: %s", err)
+		}
+		if target, err := os.Readlink(legacy); err != nil || target != p.Data {
+			t.Fatalf("expected %s to link to %s, got %q (%v)", legacy, p.Data, target, err)
+		}
+
+		// Once migrated, there is nothing left to migrate.
+		if p, _ := ResolvePaths(); p.Legacy != "" {
+			t.Fatalf("unexpectedly found the legacy directory again")
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stagingPrefix marks directories used to stage an install before it is moved into place.
+const stagingPrefix = ".staging-"
+
+// stagingTTL is how long a staging directory may exist before it is considered abandoned.
+const stagingTTL = time.Hour
+
+// NewStagingDir creates a unique staging directory inside installPath, creating the store if needed.
+// Staging inside installPath keeps the final rename on the same filesystem.
+func NewStagingDir(installPath string) (string, error) {
+	if err := os.MkdirAll(installPath, 0755); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(installPath, stagingPrefix)
+}
+
+// CleanStaging removes staging directories abandoned by interrupted installs,
+// along with the bare binary that older versions of tfvm could leave behind.
+func CleanStaging(installPath string, extension string) error {
+	entries, err := os.ReadDir(installPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		path := installPath + string(filepath.Separator) + e.Name()
+
+		if e.Name() == "terraform"+extension {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(e.Name(), stagingPrefix) {
+			continue
+		}
+
+		// Leave recent staging directories alone, they may belong to a running install.
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) < stagingTTL {
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,54 +2,46 @@ package helper
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
-	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
-
-	"github.com/PuerkitoBio/goquery"
 )
 
 // GetAvailableVersions returns a list of currently available Terraform versions.
 func GetAvailableVersions() ([]string, error) {
 	var versions []string
-	var err error = nil
-	url := "https://releases.hashicorp.com/terraform/"
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return versions, err
-	}
-	defer resp.Body.Close()
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	releases, err := getReleases()
 	if err != nil {
 		return versions, err
 	}
-	doc.Find("a[href]").Each(func(index int, item *goquery.Selection) {
-		if strings.Contains(item.Text(), "terraform") {
-			version := strings.Split(item.Text(), "_")[1]
-
-			// Filter out unsupported versions on Apple Silicon
-			if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
-				versionSlice := strings.Split(version, ".")
-				majInt, _ := strconv.Atoi(versionSlice[0])
-				minInt, _ := strconv.Atoi(versionSlice[1])
-				patchInt, _ := strconv.Atoi(versionSlice[2])
-
-				// Do not include pre-release versions or incompatible versions
-				if (majInt >= 1 && minInt >= 1 || majInt >= 1 && minInt == 0 && patchInt >= 2) && !strings.Contains(version, "-") {
-					versions = append(versions, version)
-				}
-			} else {
-				// Do not include pre-release versions
-				if !strings.Contains(version, "-") {
-					versions = append(versions, version)
-				}
+
+	for _, version := range releases {
+		// Filter out unsupported versions on Apple Silicon
+		if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+			versionSlice := strings.Split(version, ".")
+			majInt, _ := strconv.Atoi(versionSlice[0])
+			minInt, _ := strconv.Atoi(versionSlice[1])
+			patchInt, _ := strconv.Atoi(versionSlice[2])
+
+			// Do not include pre-release versions or incompatible versions
+			if (majInt >= 1 && minInt >= 1 || majInt >= 1 && minInt == 0 && patchInt >= 2) && !strings.Contains(version, "-") {
+				versions = append(versions, version)
+			}
+		} else {
+			// Do not include pre-release versions
+			if !strings.Contains(version, "-") {
+				versions = append(versions, version)
 			}
 		}
-	})
-	return versions, err
+	}
+	return versions, nil
 }
 
 // IsAvailableVersion returns true if the specified version of Terraform is in the list of available versions.
@@ -88,22 +80,35 @@ func IsAvailableVersion(version string) error {
 	return err
 }
 
-// GetInstalledVersions returns a list of all installed Terraform versions.
+// GetInstalledVersions returns a list of all installed Terraform versions, oldest first.
+// A version is installed when its directory holds a manifest.
 func GetInstalledVersions(installPath string, extension string) ([]string, error) {
 	var versions []string
 	var err error = nil
 
 	files, err := ioutil.ReadDir(installPath)
+	if os.IsNotExist(err) {
+		return versions, nil
+	}
 	if err != nil {
 		return versions, err
 	}
 	for _, f := range files {
-		v := strings.TrimPrefix(f.Name(), "terraform")
-		v = strings.TrimSuffix(v, extension)
-		versions = append(versions, v)
+		// Skip staging directories and anything that is not a version directory.
+		if strings.HasPrefix(f.Name(), ".") || !f.IsDir() {
+			continue
+		}
+
+		if _, err := ReadManifest(installPath, f.Name()); err != nil {
+			continue
+		}
+		versions = append(versions, f.Name())
 	}
 
-	return versions, err
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+	return versions, nil
 }
 
 // IsInstalledVersions returns true if the specified Terraform version is installed.
@@ -124,3 +129,45 @@ func IsInstalledVersion(installPath string, extension string, version string) er
 	err = errors.New("invalid Terraform version, run `tfvm list` for a list of installed versions")
 	return err
 }
+
+// LinkedVersion returns the external binary a version is linked to, if it was registered with `tfvm link`.
+func LinkedVersion(installPath string, extension string, version string) (string, bool) {
+	m, err := ReadManifest(installPath, version)
+	if err != nil || m.Source != SourceLinked {
+		return "", false
+	}
+	return m.Path, true
+}
+
+// CurrentVersion returns the version linked into binPath, or an empty version if none is.
+// The binary is matched against the installed versions, and only run to ask for its version if none matches.
+func CurrentVersion(installPath string, binPath string, extension string) (string, error) {
+	binFile := binPath + string(filepath.Separator) + "terraform" + extension
+	active, err := os.Stat(binFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	installed, err := GetInstalledVersions(installPath, extension)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range installed {
+		if info, err := os.Stat(BinaryPath(installPath, extension, v)); err == nil && os.SameFile(active, info) {
+			return v, nil
+		}
+	}
+
+	out, err := exec.Command(binFile, "-v").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not run %s: %w", binFile, err)
+	}
+	fields := strings.SplitN(string(out), "v", 2)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("unexpected output from %s -v", binFile)
+	}
+	return strings.Split(fields[1], "\n")[0], nil
+}
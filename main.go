@@ -4,11 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/ehassett/tfvm/internal/command"
+	"github.com/ehassett/tfvm/internal/helper"
+	"github.com/mattn/go-isatty"
 	"github.com/mitchellh/cli"
 )
 
@@ -22,76 +24,160 @@ func (ui *ui) Warn(msg string) {
 	ui.Ui.Output(msg)
 }
 
-var Ui = &ui{&cli.BasicUi{
-	Writer:      os.Stdout,
-	ErrorWriter: os.Stderr,
-	Reader:      os.Stdin,
-}}
+// ttyUi is a ui attached to a terminal, which redraws progress in place on stderr.
+type ttyUi struct {
+	*ui
+	width int
+}
+
+func (ui *ttyUi) Progress(msg string) {
+	line := strings.TrimSuffix(msg, "\n")
+
+	// Pad over whatever remains of the previous line.
+	pad := ui.width - len(line)
+	if pad < 0 {
+		pad = 0
+	}
+	fmt.Fprintf(os.Stderr, "\r%s%s", line, strings.Repeat(" ", pad))
+	ui.width = len(line)
+
+	if line != msg {
+		fmt.Fprint(os.Stderr, "\n")
+		ui.width = 0
+	}
+}
+
+var Ui = newUi()
+
+// newUi returns the ui for the process, which reports progress in place when stderr is a terminal.
+func newUi() cli.Ui {
+	base := &ui{&cli.BasicUi{
+		Writer:      os.Stdout,
+		ErrorWriter: os.Stderr,
+		Reader:      os.Stdin,
+	}}
+
+	if isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()) {
+		return &ttyUi{ui: base}
+	}
+	return base
+}
 
 func main() {
+	helper.Offline = helper.EnvBool("TFVM_OFFLINE")
+	initCommands(Ui)
+
+	args, err := globalFlags(os.Args[1:])
+	if err != nil {
+		meta.Ui.Error(err.Error())
+		os.Exit(1)
+	}
+
 	c := cli.NewCLI("tfvm", appVersion)
-	c.Args = os.Args[1:]
+	c.Args = args
 	c.Commands = Commands
+	c.HelpFunc = helpFunc
 
 	exitStatus, err := c.Run()
 	if err != nil {
-		Ui.Error(fmt.Sprintf("Error executing CLI: %s", err.Error()))
+		meta.Ui.Error(fmt.Sprintf("Error executing CLI: %s", err.Error()))
 	}
 	os.Exit(exitStatus)
 }
 
-func init() {
-	var terraformVersion, basePath, installPath, binPath, tempPath, extension string
+// globalFlags applies the flags accepted by every command and returns the remaining arguments.
+// JSON output is set up even when an error is returned, so that the error is written as JSON too.
+func globalFlags(args []string) ([]string, error) {
+	var rest []string
+	var jsonOutput bool
+	var err error
+	for _, arg := range args {
+		switch arg {
+		case "--offline", "-offline":
+			helper.Offline = true
+		case "--json", "-json", "--format=json", "-format=json":
+			jsonOutput = true
+		case "--format=text", "-format=text":
+		default:
+			if strings.HasPrefix(arg, "--format=") || strings.HasPrefix(arg, "-format=") {
+				if err == nil {
+					err = fmt.Errorf("Unknown output format %q, expected text or json", strings.SplitN(arg, "=", 2)[1])
+				}
+				continue
+			}
+			rest = append(rest, arg)
+		}
+	}
 
-	// Determine paths and extensions based on OS.
-	home, err := os.UserHomeDir()
+	// Wrap the ui once, however many times the flag is given.
+	if jsonOutput {
+		meta.Ui = command.NewJSONUi(meta.Ui)
+	}
 	if err != nil {
-		Ui.Error(fmt.Sprintf("Failed to find home directory: %s", err))
-		os.Exit(1)
+		return nil, err
 	}
-	basePath = home + string(filepath.Separator) + ".tfvm"
-	installPath = basePath + string(filepath.Separator) + "versions"
-	binPath = basePath + string(filepath.Separator) + "bin"
-	tempPath = basePath + string(filepath.Separator) + "tfvm.zip"
+	return rest, nil
+}
 
+// helpFunc extends the default help with the global options.
+func helpFunc(commands map[string]cli.CommandFactory) string {
+	helpText := `
+Global options:
+    --offline    Never use the network, as if TFVM_OFFLINE=1 were set
+    --json       Write results and errors as JSON, same as -format=json
+`
+
+	return strings.TrimRight(cli.BasicHelpFunc("tfvm")(commands), "\n") + "\n" + helpText
+}
+
+// initMeta fills in the paths and current version of meta the first time a command needs them.
+// Nothing is created here: the store is created by the first command that writes to it.
+func initMeta(m *command.Meta) error {
 	switch runtime.GOOS {
 	case "windows":
-		extension = ".exe"
-	case "linux":
-		extension = ""
-	case "darwin":
-		extension = ""
+		m.Extension = ".exe"
+	case "linux", "darwin":
+		m.Extension = ""
 	default:
-		extension = ""
-		err := errors.New("operating system could not be verified")
-		Ui.Error(fmt.Sprintf("Failed to determine extension: %s", err))
-		os.Exit(1)
+		return errors.New("operating system could not be verified")
 	}
 
-	// Create directory structure if needed.
-	if _, err := os.Stat(basePath); os.IsNotExist(err) {
-		os.Mkdir(basePath, 0755)
+	paths, err := helper.ResolvePaths()
+	if err != nil {
+		return fmt.Errorf("could not determine where to keep files: %w", err)
 	}
-	if _, err := os.Stat(installPath); os.IsNotExist(err) {
-		os.Mkdir(installPath, 0755)
+	migrated, err := helper.MigrateLegacy(paths)
+	switch {
+	case err != nil && !migrated:
+		m.Ui.Warn(fmt.Sprintf("Failed to move %s to %s, so it is still used: %s", paths.Legacy, paths.Data, err))
+		paths = paths.LegacyPaths()
+	case err != nil:
+		m.Ui.Warn(fmt.Sprintf("Failed to finish moving %s: %s", paths.Legacy, err))
+	case migrated:
+		m.Ui.Warn(fmt.Sprintf("Moved %s to %s. Add %s to your PATH in place of %s.", paths.Legacy, paths.Data,
+			paths.Data+string(filepath.Separator)+"bin", paths.Legacy+string(filepath.Separator)+"bin"))
 	}
-	if _, err := os.Stat(binPath); os.IsNotExist(err) {
-		os.Mkdir(binPath, 0755)
+	m.InstallPath = paths.Data + string(filepath.Separator) + "versions"
+	m.BinPath = paths.Data + string(filepath.Separator) + "bin"
+	helper.CachePath = paths.Cache
+	helper.ConfigPath = paths.Config
+	if err := helper.CheckConfig(); err != nil {
+		m.Ui.Warn(fmt.Sprintf("Failed to read configuration, so it is ignored: %s", err))
 	}
 
-	// Set current Terraform version if set.
-	if _, err := os.Stat(binPath + string(filepath.Separator) + "terraform" + extension); os.IsNotExist(err) {
-		terraformVersion = ""
-	} else {
-		out, err := exec.Command(binPath+string(filepath.Separator)+"terraform"+extension, "-v").Output()
-		if err != nil {
-			Ui.Error(fmt.Sprintf("Failed to determine current terraform version: %s", err))
-			os.Exit(1)
-		}
-		tmp := strings.Split(string(out), "v")[1]
-		terraformVersion = strings.Split(tmp, "\n")[0]
+	// Clean up after any interrupted installs.
+	os.Remove(paths.Data + string(filepath.Separator) + "tfvm.zip")
+	if err := helper.CleanStaging(m.InstallPath, m.Extension); err != nil {
+		m.Ui.Warn(fmt.Sprintf("Failed to clean up interrupted installs: %s", err))
+	}
+	if err := helper.MigrateLayout(m.InstallPath, m.Extension); err != nil {
+		m.Ui.Warn(fmt.Sprintf("Failed to migrate installed versions to the new layout: %s", err))
 	}
 
-	// Pass initialized values to initCommands for Meta.
-	initCommands(terraformVersion, installPath, binPath, tempPath, extension, Ui)
+	// A broken current binary should not stop tfvm use from replacing it.
+	m.TerraformVersion, err = helper.CurrentVersion(m.InstallPath, m.BinPath, m.Extension)
+	if err != nil {
+		m.Ui.Warn(fmt.Sprintf("Failed to determine current terraform version: %s", err))
+	}
+	return nil
 }
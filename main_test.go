@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+// TestGlobalFlags tests applying the global flags to the Meta every command is created with.
+func TestGlobalFlags(t *testing.T) {
+	old := meta.Ui
+	defer func() { meta.Ui = old }()
+
+	// errorMessage decodes the single JSON error document in out.
+	errorMessage := func(t *testing.T, out string) string {
+		var doc struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		dec := json.NewDecoder(strings.NewReader(out))
+		if err := dec.Decode(&doc); err != nil {
+			t.Fatalf("expected a JSON document, got %q: %s", out, err)
+		}
+		if dec.More() {
+			t.Fatalf("expected a single JSON document, got %q", out)
+		}
+		return doc.Error.Message
+	}
+
+	// Repeat the JSON flag and expect errors to be written as a single JSON document.
+	t.Run("repeated json flag", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		meta.Ui = ui
+
+		args, err := globalFlags([]string{"--json", "list", "-format=json"})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(args) != 1 || args[0] != "list" {
+			t.Fatalf("unexpected arguments %v", args)
+		}
+
+		meta.Ui.Error("failed")
+		if message := errorMessage(t, ui.OutputWriter.String()); message != "failed" {
+			t.Fatalf("unexpected message %q", message)
+		}
+	})
+
+	// Pass an unknown format with the JSON flag and expect the error to be written as JSON.
+	t.Run("unknown format", func(t *testing.T) {
+		ui := new(cli.MockUi)
+		meta.Ui = ui
+
+		_, err := globalFlags([]string{"--format=xml", "--json", "list"})
+		if err == nil {
+			t.Fatalf("expected an error for an unknown format")
+		}
+
+		meta.Ui.Error(err.Error())
+		if message := errorMessage(t, ui.OutputWriter.String()); !strings.Contains(message, "Unknown output format") {
+			t.Fatalf("unexpected message %q", message)
+		}
+	})
+}